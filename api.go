@@ -0,0 +1,184 @@
+package libhoney
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// defaultAPITimeout bounds requests to the Honeycomb API made outside of the
+// batch sender, in addition to any deadline on the request's context.
+const defaultAPITimeout = 30 * time.Second
+
+// ErrInvalidAPIKey matches (via errors.Is) errors returned when Honeycomb
+// rejects the API key.
+var ErrInvalidAPIKey = errors.New("Honeycomb API key is invalid")
+
+// ErrNotFound matches (via errors.Is) errors returned when the Honeycomb API
+// responds that the requested resource doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrMissingAPIKey is returned when a request to the Honeycomb API is attempted
+// without an API key.
+var ErrMissingAPIKey = errors.New("no Honeycomb API key configured")
+
+// APIError is returned when the Honeycomb API responds to a request with a
+// non-2xx status.
+type APIError struct {
+	// StatusCode is the HTTP status returned by the API.
+	StatusCode int
+	// Message is the error message from the response body, if there was one.
+	Message string
+	// Body is the raw response body.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("Honeycomb API returned %d: %s", e.StatusCode, msg)
+}
+
+// Is reports whether e is equivalent to target. An APIError with a 401 status
+// is ErrInvalidAPIKey and one with a 404 status is ErrNotFound.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInvalidAPIKey:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// userAgent returns the User-Agent sent with every request to Honeycomb: the
+// same value the batch sender uses.
+func userAgent() string {
+	ua := fmt.Sprintf("libhoney-go/%s", version)
+	if UserAgentAddition != "" {
+		ua = fmt.Sprintf("%s %s", ua, strings.TrimSpace(UserAgentAddition))
+	}
+	return ua
+}
+
+// transportFor picks the RoundTripper for API requests: rt if set, otherwise
+// the RoundTripper of sender if it is a transmission.Honeycomb (so API
+// requests get its TLS, proxy and connection settings), otherwise nil
+// (meaning http.DefaultTransport).
+func transportFor(rt http.RoundTripper, sender transmission.Sender) http.RoundTripper {
+	if rt != nil {
+		return rt
+	}
+	if h, ok := sender.(*transmission.Honeycomb); ok {
+		return h.RoundTripper()
+	}
+	return nil
+}
+
+// apiClient makes JSON requests to the Honeycomb API, sharing the host,
+// authentication and User-Agent handling used by the batch sender.
+type apiClient struct {
+	apiHost    string
+	apiKey     string
+	httpClient *http.Client
+	// modifyRequest, if set, is applied to each request just before it is
+	// sent, as the batch sender does for its own requests
+	modifyRequest func(*http.Request) error
+}
+
+// newAPIClient returns a client for the API at apiHost. The transport is
+// picked by transportFor; if sender is a transmission.Honeycomb its Headers
+// and RequestModifier are applied to every request too.
+func newAPIClient(apiHost, apiKey string, rt http.RoundTripper, sender transmission.Sender) *apiClient {
+	if apiHost == "" {
+		apiHost = defaultAPIHost
+	}
+	a := &apiClient{
+		apiHost: apiHost,
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Transport: transportFor(rt, sender),
+			Timeout:   defaultAPITimeout,
+		},
+	}
+	if h, ok := sender.(*transmission.Honeycomb); ok {
+		a.modifyRequest = h.ModifyRequest
+	}
+	return a
+}
+
+// do sends a request to the API at the path made by joining elems. If reqBody
+// is not nil it is encoded as JSON; if respBody is not nil the response is
+// decoded into it. Non-2xx responses are returned as *APIError.
+func (a *apiClient) do(ctx context.Context, method string, reqBody, respBody interface{}, elems ...string) error {
+	if a.apiKey == "" {
+		return ErrMissingAPIKey
+	}
+	u, err := url.Parse(a.apiHost)
+	if err != nil {
+		return fmt.Errorf("Error parsing API URL: %s", err)
+	}
+	u.Path = path.Join(append([]string{u.Path}, elems...)...)
+
+	var body io.Reader
+	if reqBody != nil {
+		encoded, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Add("X-Honeycomb-Team", a.apiKey)
+
+	if a.modifyRequest != nil {
+		if err := a.modifyRequest(req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: respBytes}
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBytes, &errBody) == nil {
+			apiErr.Message = errBody.Error
+		}
+		return apiErr
+	}
+	if respBody != nil && len(respBytes) > 0 {
+		return json.Unmarshal(respBytes, respBody)
+	}
+	return nil
+}
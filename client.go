@@ -1,9 +1,11 @@
 package libhoney
 
 import (
+	"context"
 	"errors"
 	"sync"
 
+	"github.com/honeycombio/libhoney-go/internal/logging"
 	"github.com/honeycombio/libhoney-go/transmission"
 )
 
@@ -22,6 +24,21 @@ type Client struct {
 	oneTx      sync.Once
 	oneLogger  sync.Once
 	oneBuilder sync.Once
+
+	// poolEvents makes builders take events from eventPool
+	poolEvents bool
+
+	// runtimeStop and runtimeDone control the collector started by
+	// StartRuntimeMetrics
+	runtimeStop chan struct{}
+	runtimeDone chan struct{}
+	runtimeLock sync.Mutex
+}
+
+// statsSender is implemented by transmission Senders (such as
+// transmission.Honeycomb) that keep counters about what they've sent.
+type statsSender interface {
+	Stats() transmission.Stats
 }
 
 // ClientConfig is a subset of the global libhoney config that focuses on the
@@ -62,10 +79,34 @@ type ClientConfig struct {
 	// Intended for human consumption during development to understand what the
 	// SDK is doing and diagnose trouble emitting events.
 	Logger Logger
+
+	// Metrics receives the default Honeycomb transmission's internal counters
+	// and gauges. Defaults to a muted statsd client. Use
+	// transmission.NewExpvarMetrics or transmission.NewPrometheusMetrics to
+	// export them. Ignored if Transmission is set.
+	Metrics transmission.Metrics
+
+	// VerifyAPIKey makes NewClient check APIKey with the Honeycomb API (see
+	// VerifyAPIKeyContext) and return an error instead of a Client if the key
+	// is missing or rejected, or the API can't be reached within
+	// DefaultVerifyAPIKeyTimeout.
+	VerifyAPIKey bool
+
+	// PoolEvents reduces allocations for high volumes of events by recycling
+	// each event (with its field map) once the transmission has finished with
+	// it. With PoolEvents set, an event must not be used in any way after
+	// Send or SendPresampled returns without error, since it may already be
+	// in use as a new event. Senders that keep events, such as
+	// transmission.MockSender, never recycle them.
+	PoolEvents bool
 }
 
-// NewClient creates a Client with defaults correctly set
+// NewClient creates a Client with defaults correctly set. It returns an error
+// if conf fails Validate.
 func NewClient(conf ClientConfig) (*Client, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
 	if conf.SampleRate == 0 {
 		conf.SampleRate = defaultSampleRate
 	}
@@ -75,12 +116,30 @@ func NewClient(conf ClientConfig) (*Client, error) {
 	if conf.Dataset == "" {
 		conf.Dataset = defaultDataset
 	}
+	if conf.Metrics == nil {
+		conf.Metrics = sd
+	}
 
 	c := &Client{
-		logger: conf.Logger,
+		logger:     conf.Logger,
+		poolEvents: conf.PoolEvents,
 	}
 	c.ensureLogger()
 
+	if conf.VerifyAPIKey {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultVerifyAPIKeyTimeout)
+		defer cancel()
+		_, err := VerifyAPIKeyContext(ctx, Config{
+			APIKey:       conf.APIKey,
+			APIHost:      conf.APIHost,
+			Transmission: conf.Transmission,
+		})
+		if err != nil {
+			logging.Error(c.logger, "API key verification failed", "err", err)
+			return nil, err
+		}
+	}
+
 	if conf.Transmission == nil {
 		c.transmission = &transmission.Honeycomb{
 			MaxBatchSize:         DefaultMaxBatchSize,
@@ -89,13 +148,13 @@ func NewClient(conf ClientConfig) (*Client, error) {
 			PendingWorkCapacity:  DefaultPendingWorkCapacity,
 			UserAgentAddition:    UserAgentAddition,
 			Logger:               c.logger,
-			Metrics:              sd,
+			Metrics:              conf.Metrics,
 		}
 	} else {
 		c.transmission = conf.Transmission
 	}
 	if err := c.transmission.Start(); err != nil {
-		c.logger.Printf("transmission client failed to start: %s", err.Error())
+		logging.Error(c.logger, "transmission client failed to start", "err", err)
 		return nil, err
 	}
 
@@ -150,7 +209,8 @@ func (c *Client) ensureBuilder() {
 // call Close() before app termination.
 func (c *Client) Close() {
 	c.ensureLogger()
-	c.logger.Printf("closing libhoney client")
+	logging.Info(c.logger, "closing libhoney client")
+	c.stopRuntimeMetrics()
 	if c.transmission != nil {
 		c.transmission.Stop()
 	}
@@ -165,7 +225,7 @@ func (c *Client) Close() {
 // parts of your program are calling Send
 func (c *Client) Flush() {
 	c.ensureLogger()
-	c.logger.Printf("flushing libhoney client")
+	logging.Debug(c.logger, "flushing libhoney client")
 	if c.transmission != nil {
 		c.transmission.Stop()
 		c.transmission.Start()
@@ -179,6 +239,18 @@ func (c *Client) TxResponses() chan transmission.Response {
 	return c.transmission.TxResponses()
 }
 
+// Stats returns a snapshot of the transmission's counters: events queued,
+// sent and dropped, batches, retries, bytes sent and the current queue length.
+// If the client's Sender doesn't keep counters (only transmission.Honeycomb
+// does), the snapshot is empty.
+func (c *Client) Stats() transmission.Stats {
+	c.ensureTransmission()
+	if s, ok := c.transmission.(statsSender); ok {
+		return s.Stats()
+	}
+	return transmission.Stats{Dropped: map[string]int64{}}
+}
+
 // AddDynamicField takes a field name and a function that will generate values
 // for that metric. The function is called once every time a NewEvent() is
 // created and added as a field (with name as the key) to the newly created
@@ -189,6 +261,22 @@ func (c *Client) AddDynamicField(name string, fn func() interface{}) error {
 	return c.builder.AddDynamicField(name, fn)
 }
 
+// AddDynamicFieldFunc adds a dynamic field to the Client's scope whose value is
+// computed when each event is sent. See Builder.AddDynamicFieldFunc.
+func (c *Client) AddDynamicFieldFunc(name string, fn func(context.Context, *Event) interface{}) error {
+	c.ensureTransmission()
+	c.ensureBuilder()
+	return c.builder.AddDynamicFieldFunc(name, fn)
+}
+
+// RemoveDynamicField removes the dynamic fields called name from the Client's
+// scope. See Builder.RemoveDynamicField.
+func (c *Client) RemoveDynamicField(name string) bool {
+	c.ensureTransmission()
+	c.ensureBuilder()
+	return c.builder.RemoveDynamicField(name)
+}
+
 // AddField adds a Field to the Client's scope. This metric will be inherited by
 // all builders and events.
 func (c *Client) AddField(name string, val interface{}) {
@@ -221,3 +221,31 @@ func TestEnsureBuilderRaces(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestClientStats(t *testing.T) {
+	// senders that don't keep counters produce an empty snapshot
+	c, err := NewClient(ClientConfig{Transmission: &transmission.MockSender{}})
+	testOK(t, err)
+	stats := c.Stats()
+	testEquals(t, stats.QueueLength, 0)
+	testEquals(t, stats.Queued, int64(0))
+
+	tx := &transmission.Honeycomb{
+		PendingWorkCapacity:  10,
+		MaxBatchSize:         10,
+		BatchTimeout:         time.Hour,
+		MaxConcurrentBatches: 1,
+	}
+	c, err = NewClient(ClientConfig{APIKey: "foo", Transmission: tx})
+	testOK(t, err)
+	defer c.Close()
+	ev := c.NewEvent()
+	ev.AddField("a", 1)
+	testOK(t, ev.Send())
+	stats = c.Stats()
+	testEquals(t, stats.Queued, int64(1))
+	testEquals(t, stats.Dropped[transmission.DropReasonQueueOverflow], int64(0))
+}
+
+// the default statsd client should receive the optional batch distributions
+var _ transmission.DistributionMetrics = sd
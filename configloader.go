@@ -0,0 +1,220 @@
+package libhoney
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+	"gopkg.in/yaml.v3"
+)
+
+// Sender types accepted by the "sender" setting of ConfigFromEnv and
+// ConfigFromFile.
+const (
+	SenderHoneycomb = "honeycomb"
+	SenderWriter    = "writer"
+	SenderDiscard   = "discard"
+)
+
+// configSetting describes one Config field that can be loaded from the
+// environment or a file. Every value is handled as a string so that both
+// sources share the same parsing and validation.
+type configSetting struct {
+	// name is the key used in config files
+	name string
+	// env is the environment variable name
+	env   string
+	apply func(conf *Config, val string) error
+}
+
+var configSettings = []configSetting{
+	{"api_key", "HONEYCOMB_API_KEY", func(c *Config, v string) error {
+		c.APIKey = v
+		return nil
+	}},
+	{"dataset", "HONEYCOMB_DATASET", func(c *Config, v string) error {
+		c.Dataset = v
+		return nil
+	}},
+	{"api_host", "HONEYCOMB_API_HOST", func(c *Config, v string) error {
+		c.APIHost = v
+		return nil
+	}},
+	{"sample_rate", "HONEYCOMB_SAMPLE_RATE", func(c *Config, v string) (err error) {
+		c.SampleRate, err = parsePositiveUint(v)
+		return err
+	}},
+	{"max_batch_size", "HONEYCOMB_MAX_BATCH_SIZE", func(c *Config, v string) (err error) {
+		c.MaxBatchSize, err = parsePositiveUint(v)
+		return err
+	}},
+	{"send_frequency", "HONEYCOMB_SEND_FREQUENCY", func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("must be a positive duration such as \"100ms\"")
+		}
+		c.SendFrequency = d
+		return nil
+	}},
+	{"max_concurrent_batches", "HONEYCOMB_MAX_CONCURRENT_BATCHES", func(c *Config, v string) (err error) {
+		c.MaxConcurrentBatches, err = parsePositiveUint(v)
+		return err
+	}},
+	{"pending_work_capacity", "HONEYCOMB_PENDING_WORK_CAPACITY", func(c *Config, v string) (err error) {
+		c.PendingWorkCapacity, err = parsePositiveUint(v)
+		return err
+	}},
+	{"disable_compression", "HONEYCOMB_DISABLE_COMPRESSION", func(c *Config, v string) (err error) {
+		c.DisableCompression, err = parseBool(v)
+		return err
+	}},
+	{"enable_msgpack", "HONEYCOMB_ENABLE_MSGPACK", func(c *Config, v string) (err error) {
+		c.EnableMsgpackEncoding, err = parseBool(v)
+		return err
+	}},
+	{"block_on_send", "HONEYCOMB_BLOCK_ON_SEND", func(c *Config, v string) (err error) {
+		c.BlockOnSend, err = parseBool(v)
+		return err
+	}},
+	{"block_on_response", "HONEYCOMB_BLOCK_ON_RESPONSE", func(c *Config, v string) (err error) {
+		c.BlockOnResponse, err = parseBool(v)
+		return err
+	}},
+	{"sender", "HONEYCOMB_SENDER", func(c *Config, v string) error {
+		switch strings.ToLower(v) {
+		case SenderHoneycomb:
+			c.Transmission = nil
+		case SenderWriter:
+			c.Transmission = &transmission.WriterSender{}
+		case SenderDiscard:
+			c.Transmission = &transmission.DiscardSender{}
+		default:
+			return fmt.Errorf("must be one of %q, %q or %q", SenderHoneycomb, SenderWriter, SenderDiscard)
+		}
+		return nil
+	}},
+}
+
+func parsePositiveUint(v string) (uint, error) {
+	n, err := strconv.ParseUint(v, 10, 0)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("must be a positive integer")
+	}
+	return uint(n), nil
+}
+
+func parseBool(v string) (bool, error) {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("must be true or false")
+	}
+	return b, nil
+}
+
+// ConfigFromEnv returns a Config populated from HONEYCOMB_* environment
+// variables:
+//
+//	HONEYCOMB_API_KEY, HONEYCOMB_DATASET, HONEYCOMB_API_HOST,
+//	HONEYCOMB_SAMPLE_RATE, HONEYCOMB_MAX_BATCH_SIZE,
+//	HONEYCOMB_SEND_FREQUENCY (a duration, e.g. "100ms"),
+//	HONEYCOMB_MAX_CONCURRENT_BATCHES, HONEYCOMB_PENDING_WORK_CAPACITY,
+//	HONEYCOMB_DISABLE_COMPRESSION, HONEYCOMB_ENABLE_MSGPACK,
+//	HONEYCOMB_BLOCK_ON_SEND, HONEYCOMB_BLOCK_ON_RESPONSE and
+//	HONEYCOMB_SENDER (one of "honeycomb", "writer" or "discard").
+//
+// Unset or empty variables leave the corresponding field at its zero value,
+// so Init and NewClient apply the usual defaults. Fields you set on the
+// returned Config before calling Init take precedence over the environment.
+// An error names every variable that has an invalid value.
+func ConfigFromEnv() (Config, error) {
+	var conf Config
+	err := applyEnv(&conf)
+	return conf, err
+}
+
+// ConfigFromFile returns a Config populated from a YAML (.yaml or .yml) or JSON
+// (.json) file whose keys are the lower-case names of the settings read by
+// ConfigFromEnv, without the HONEYCOMB_ prefix (e.g. api_key, send_frequency).
+// Unknown keys are an error.
+//
+// HONEYCOMB_* environment variables are applied on top of the file, so the
+// order of precedence, highest first, is: fields you set on the returned
+// Config, the environment, the file, and finally the library defaults.
+func ConfigFromFile(path string) (Config, error) {
+	var conf Config
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return conf, err
+	}
+
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(contents))
+		dec.UseNumber()
+		err = dec.Decode(&raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, &raw)
+	default:
+		return conf, fmt.Errorf("%s: unsupported config file extension; use .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return conf, fmt.Errorf("%s: %s", path, err)
+	}
+
+	var problems []string
+	known := map[string]bool{}
+	for _, s := range configSettings {
+		known[s.name] = true
+		val, ok := raw[s.name]
+		if !ok || val == nil {
+			continue
+		}
+		if err := s.apply(&conf, fmt.Sprint(val)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid value %q: %s", s.name, fmt.Sprint(val), err))
+		}
+	}
+	var unknown []string
+	for k := range raw {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		problems = append(problems, fmt.Sprintf("%s: unknown setting", k))
+	}
+	if len(problems) > 0 {
+		return conf, fmt.Errorf("%s: invalid config: %s", path, strings.Join(problems, "; "))
+	}
+
+	err = applyEnv(&conf)
+	return conf, err
+}
+
+// applyEnv overwrites fields of conf with any HONEYCOMB_* environment variables
+// that are set.
+func applyEnv(conf *Config) error {
+	var problems []string
+	for _, s := range configSettings {
+		val := os.Getenv(s.env)
+		if val == "" {
+			continue
+		}
+		if err := s.apply(conf, val); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid value %q: %s", s.env, val, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid environment: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package libhoney
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// setenv sets vals in the environment and returns a function that unsets
+// them again.
+func setenv(vals map[string]string) func() {
+	for k, v := range vals {
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k := range vals {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	testOK(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	defer setenv(map[string]string{
+		"HONEYCOMB_API_KEY":        "key",
+		"HONEYCOMB_DATASET":        "ds",
+		"HONEYCOMB_SAMPLE_RATE":    "10",
+		"HONEYCOMB_SEND_FREQUENCY": "250ms",
+		"HONEYCOMB_ENABLE_MSGPACK": "true",
+		"HONEYCOMB_SENDER":         "writer",
+	})()
+	conf, err := ConfigFromEnv()
+	testOK(t, err)
+	testEquals(t, conf.APIKey, "key")
+	testEquals(t, conf.Dataset, "ds")
+	testEquals(t, conf.SampleRate, uint(10))
+	testEquals(t, conf.SendFrequency, 250*time.Millisecond)
+	testEquals(t, conf.EnableMsgpackEncoding, true)
+	_, isWriter := conf.Transmission.(*transmission.WriterSender)
+	testEquals(t, isWriter, true)
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	defer setenv(map[string]string{
+		"HONEYCOMB_SAMPLE_RATE":   "0",
+		"HONEYCOMB_BLOCK_ON_SEND": "sometimes",
+		"HONEYCOMB_SENDER":        "pigeon",
+	})()
+	_, err := ConfigFromEnv()
+	testErr(t, err)
+	for _, name := range []string{"HONEYCOMB_SAMPLE_RATE", "HONEYCOMB_BLOCK_ON_SEND", "HONEYCOMB_SENDER"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to mention %s, got %q", name, err)
+		}
+	}
+}
+
+func TestConfigFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libhoney-config")
+	testOK(t, err)
+	defer os.RemoveAll(dir)
+	yamlPath := writeConfigFile(t, dir, "honeycomb.yaml", `
+api_key: filekey
+dataset: fileds
+max_batch_size: 20
+disable_compression: true
+sender: discard
+`)
+	conf, err := ConfigFromFile(yamlPath)
+	testOK(t, err)
+	testEquals(t, conf.APIKey, "filekey")
+	testEquals(t, conf.MaxBatchSize, uint(20))
+	testEquals(t, conf.DisableCompression, true)
+	_, isDiscard := conf.Transmission.(*transmission.DiscardSender)
+	testEquals(t, isDiscard, true)
+
+	jsonPath := writeConfigFile(t, dir, "honeycomb.json", `{"api_key": "filekey", "sample_rate": 5}`)
+	conf, err = ConfigFromFile(jsonPath)
+	testOK(t, err)
+	testEquals(t, conf.SampleRate, uint(5))
+
+	// the environment wins over the file
+	defer setenv(map[string]string{"HONEYCOMB_API_KEY": "envkey"})()
+	conf, err = ConfigFromFile(jsonPath)
+	testOK(t, err)
+	testEquals(t, conf.APIKey, "envkey")
+}
+
+func TestConfigFromFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libhoney-config")
+	testOK(t, err)
+	defer os.RemoveAll(dir)
+	path := writeConfigFile(t, dir, "honeycomb.yaml", `
+sample_rate: lots
+api_kye: typo
+`)
+	_, err = ConfigFromFile(path)
+	testErr(t, err)
+	if !strings.Contains(err.Error(), "sample_rate") || !strings.Contains(err.Error(), "api_kye: unknown setting") {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	_, err = ConfigFromFile(writeConfigFile(t, dir, "honeycomb.toml", ""))
+	testErr(t, err)
+}
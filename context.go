@@ -0,0 +1,67 @@
+package libhoney
+
+import "context"
+
+// contextKey is unexported so that values stored by this package can't collide
+// with context values set by any other package.
+type contextKey int
+
+const (
+	eventContextKey contextKey = iota
+	builderContextKey
+)
+
+// ContextWithEvent returns a copy of ctx carrying ev. Use EventFromContext to
+// retrieve it further down the call stack so that many functions can enrich
+// the same event without passing it around explicitly.
+func ContextWithEvent(ctx context.Context, ev *Event) context.Context {
+	return context.WithValue(ctx, eventContextKey, ev)
+}
+
+// EventFromContext returns the event stored in ctx by ContextWithEvent, or nil
+// if there isn't one.
+func EventFromContext(ctx context.Context) *Event {
+	if ctx == nil {
+		return nil
+	}
+	if ev, ok := ctx.Value(eventContextKey).(*Event); ok {
+		return ev
+	}
+	return nil
+}
+
+// ContextWithBuilder returns a copy of ctx carrying b. Use BuilderFromContext
+// to retrieve it further down the call stack.
+func ContextWithBuilder(ctx context.Context, b *Builder) context.Context {
+	return context.WithValue(ctx, builderContextKey, b)
+}
+
+// BuilderFromContext returns the builder stored in ctx by ContextWithBuilder,
+// or nil if there isn't one.
+func BuilderFromContext(ctx context.Context) *Builder {
+	if ctx == nil {
+		return nil
+	}
+	if b, ok := ctx.Value(builderContextKey).(*Builder); ok {
+		return b
+	}
+	return nil
+}
+
+// AddFieldCtx adds a field to the event stored in ctx. If ctx has no event it
+// does nothing, so it is safe to call from library code that doesn't know
+// whether its caller is instrumented.
+func AddFieldCtx(ctx context.Context, key string, val interface{}) {
+	if ev := EventFromContext(ctx); ev != nil {
+		ev.AddField(key, val)
+	}
+}
+
+// AddCtx adds a complex data type to the event stored in ctx, as Event.Add
+// does. If ctx has no event it does nothing and returns nil.
+func AddCtx(ctx context.Context, data interface{}) error {
+	if ev := EventFromContext(ctx); ev != nil {
+		return ev.Add(data)
+	}
+	return nil
+}
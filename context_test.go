@@ -0,0 +1,39 @@
+package libhoney
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEventContext(t *testing.T) {
+	resetPackageVars()
+	ctx := context.Background()
+	testEquals(t, EventFromContext(ctx), (*Event)(nil))
+
+	// adding to a context without an event is a safe no-op
+	AddFieldCtx(ctx, "foo", "bar")
+	testOK(t, AddCtx(ctx, map[string]interface{}{"foo": "bar"}))
+
+	ev := NewEvent()
+	ctx = ContextWithEvent(ctx, ev)
+	testEquals(t, EventFromContext(ctx), ev)
+
+	AddFieldCtx(ctx, "foo", "bar")
+	testOK(t, AddCtx(ctx, map[string]interface{}{"baz": 1}))
+	testEquals(t, ev.data["foo"], "bar")
+	testEquals(t, ev.data["baz"], 1)
+
+	testEquals(t, EventFromContext(nil), (*Event)(nil))
+}
+
+func TestBuilderContext(t *testing.T) {
+	resetPackageVars()
+	ctx := context.Background()
+	testEquals(t, BuilderFromContext(ctx), (*Builder)(nil))
+
+	b := NewBuilder()
+	ctx = ContextWithBuilder(ctx, b)
+	testEquals(t, BuilderFromContext(ctx), b)
+	// the builder and event are stored independently
+	testEquals(t, EventFromContext(ctx), (*Event)(nil))
+}
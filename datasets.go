@@ -0,0 +1,173 @@
+package libhoney
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ColumnType is the type Honeycomb uses to interpret a column's values.
+type ColumnType string
+
+// The column types understood by Honeycomb.
+const (
+	ColumnTypeString  ColumnType = "string"
+	ColumnTypeFloat   ColumnType = "float"
+	ColumnTypeInteger ColumnType = "integer"
+	ColumnTypeBoolean ColumnType = "boolean"
+)
+
+// Dataset is a Honeycomb dataset definition.
+type Dataset struct {
+	// Name is the dataset name used when sending events.
+	Name string `json:"name"`
+	// Slug is the URL-safe form of Name that identifies the dataset in the
+	// API. It is assigned by Honeycomb.
+	Slug string `json:"slug,omitempty"`
+	// Description is shown alongside the dataset in the Honeycomb UI.
+	Description string `json:"description,omitempty"`
+	// ExpandJSONDepth is how many levels of nested JSON Honeycomb unpacks into
+	// separate columns.
+	ExpandJSONDepth int `json:"expand_json_depth,omitempty"`
+	// CreatedAt is assigned by Honeycomb.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// Column is a column definition within a dataset.
+type Column struct {
+	// ID is assigned by Honeycomb when the column is created.
+	ID string `json:"id,omitempty"`
+	// KeyName is the field name the column holds.
+	KeyName string `json:"key_name"`
+	// Type is how Honeycomb interprets the column's values.
+	Type ColumnType `json:"type,omitempty"`
+	// Description is shown alongside the column in the Honeycomb UI.
+	Description string `json:"description"`
+	// Hidden columns are left out of autocomplete and raw data field lists.
+	Hidden bool `json:"hidden"`
+	// CreatedAt, UpdatedAt and LastWritten are maintained by Honeycomb.
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	LastWritten *time.Time `json:"last_written,omitempty"`
+}
+
+// DatasetsClient manages dataset and column definitions through the Honeycomb
+// datasets (/1/datasets) and columns (/1/columns/<dataset>) APIs, so that a
+// service can declare its schema at startup. Get one from Client.Datasets.
+// Errors from the API are returned as *APIError; use errors.Is with
+// ErrInvalidAPIKey or ErrNotFound to tell common failures apart.
+type DatasetsClient struct {
+	api *apiClient
+}
+
+// Datasets returns a DatasetsClient using the client's API host, API key and,
+// if its transmission is a transmission.Honeycomb, that transmission's
+// RoundTripper. The key needs the "create datasets" permission to create
+// datasets and the "manage queries and columns" permission to change columns.
+func (c *Client) Datasets() *DatasetsClient {
+	c.ensureTransmission()
+	c.ensureBuilder()
+	return &DatasetsClient{
+		api: newAPIClient(c.builder.APIHost, c.builder.WriteKey, nil, c.transmission),
+	}
+}
+
+// Datasets returns a DatasetsClient using the package-level client's settings.
+// See Client.Datasets.
+func Datasets() *DatasetsClient {
+	return dc.Datasets()
+}
+
+// Create creates a dataset, returning it as stored by Honeycomb. Creating a
+// dataset that already exists returns the existing dataset.
+func (d *DatasetsClient) Create(ctx context.Context, ds Dataset) (Dataset, error) {
+	if ds.Name == "" {
+		return Dataset{}, errors.New("can't create a dataset without a name")
+	}
+	var created Dataset
+	err := d.api.do(ctx, "POST", ds, &created, "1", "datasets")
+	return created, err
+}
+
+// Get returns the dataset with the given slug.
+func (d *DatasetsClient) Get(ctx context.Context, slug string) (Dataset, error) {
+	var ds Dataset
+	err := d.api.do(ctx, "GET", nil, &ds, "1", "datasets", slug)
+	return ds, err
+}
+
+// ListColumns returns the columns of the dataset with the given slug.
+func (d *DatasetsClient) ListColumns(ctx context.Context, dataset string) ([]Column, error) {
+	var cols []Column
+	err := d.api.do(ctx, "GET", nil, &cols, "1", "columns", dataset)
+	return cols, err
+}
+
+// CreateColumn defines a new column on the dataset with the given slug.
+func (d *DatasetsClient) CreateColumn(ctx context.Context, dataset string, col Column) (Column, error) {
+	if col.KeyName == "" {
+		return Column{}, errors.New("can't create a column without a key name")
+	}
+	var created Column
+	err := d.api.do(ctx, "POST", col, &created, "1", "columns", dataset)
+	return created, err
+}
+
+// UpdateColumn replaces the type, description and hidden flag of the column
+// with col.ID.
+func (d *DatasetsClient) UpdateColumn(ctx context.Context, dataset string, col Column) (Column, error) {
+	if col.ID == "" {
+		return Column{}, errors.New("can't update a column without an ID")
+	}
+	var updated Column
+	err := d.api.do(ctx, "PUT", col, &updated, "1", "columns", dataset, col.ID)
+	return updated, err
+}
+
+// SetColumnType sets the type of the column named keyName.
+func (d *DatasetsClient) SetColumnType(ctx context.Context, dataset, keyName string, typ ColumnType) (Column, error) {
+	return d.modifyColumn(ctx, dataset, keyName, func(c *Column) { c.Type = typ })
+}
+
+// SetColumnDescription sets the description of the column named keyName.
+func (d *DatasetsClient) SetColumnDescription(ctx context.Context, dataset, keyName, description string) (Column, error) {
+	return d.modifyColumn(ctx, dataset, keyName, func(c *Column) { c.Description = description })
+}
+
+// SetColumnHidden hides or unhides the column named keyName.
+func (d *DatasetsClient) SetColumnHidden(ctx context.Context, dataset, keyName string, hidden bool) (Column, error) {
+	return d.modifyColumn(ctx, dataset, keyName, func(c *Column) { c.Hidden = hidden })
+}
+
+// ColumnNotFoundError is returned when a column to be modified doesn't exist
+// in its dataset. It matches ErrNotFound under errors.Is.
+type ColumnNotFoundError struct {
+	Dataset string
+	KeyName string
+}
+
+func (e *ColumnNotFoundError) Error() string {
+	return fmt.Sprintf("column %q in dataset %q: %s", e.KeyName, e.Dataset, ErrNotFound)
+}
+
+// Is reports whether target is ErrNotFound.
+func (e *ColumnNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// modifyColumn looks up the column named keyName, applies fn to it and saves
+// the result. If there's no such column the error matches ErrNotFound.
+func (d *DatasetsClient) modifyColumn(ctx context.Context, dataset, keyName string, fn func(*Column)) (Column, error) {
+	cols, err := d.ListColumns(ctx, dataset)
+	if err != nil {
+		return Column{}, err
+	}
+	for _, col := range cols {
+		if col.KeyName == keyName {
+			fn(&col)
+			return d.UpdateColumn(ctx, dataset, col)
+		}
+	}
+	return Column{}, &ColumnNotFoundError{Dataset: dataset, KeyName: keyName}
+}
@@ -0,0 +1,102 @@
+package libhoney
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+func TestDatasets(t *testing.T) {
+	columns := map[string]*Column{
+		"c1": {ID: "c1", KeyName: "status", Type: ColumnTypeString},
+	}
+	var lastPut Column
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testEquals(t, r.Header.Get("X-Honeycomb-Team"), "schemakey")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /1/datasets":
+			var ds Dataset
+			json.NewDecoder(r.Body).Decode(&ds)
+			ds.Slug = "my-service"
+			json.NewEncoder(w).Encode(ds)
+		case "GET /1/datasets/my-service":
+			json.NewEncoder(w).Encode(Dataset{Name: "my service", Slug: "my-service"})
+		case "GET /1/columns/my-service":
+			list := []*Column{}
+			for _, c := range columns {
+				list = append(list, c)
+			}
+			json.NewEncoder(w).Encode(list)
+		case "POST /1/columns/my-service":
+			var col Column
+			json.NewDecoder(r.Body).Decode(&col)
+			col.ID = "c2"
+			columns[col.ID] = &col
+			json.NewEncoder(w).Encode(col)
+		case "PUT /1/columns/my-service/c1":
+			json.NewDecoder(r.Body).Decode(&lastPut)
+			columns["c1"] = &lastPut
+			json.NewEncoder(w).Encode(lastPut)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewClient(ClientConfig{
+		APIKey:       "schemakey",
+		APIHost:      server.URL,
+		Transmission: &transmission.MockSender{},
+	})
+	testOK(t, err)
+	ctx := context.Background()
+	d := c.Datasets()
+
+	ds, err := d.Create(ctx, Dataset{Name: "my service", Description: "the service"})
+	testOK(t, err)
+	testEquals(t, ds.Slug, "my-service")
+	testEquals(t, ds.Description, "the service")
+
+	ds, err = d.Get(ctx, "my-service")
+	testOK(t, err)
+	testEquals(t, ds.Name, "my service")
+
+	_, err = d.Get(ctx, "missing")
+	apiErr, ok := err.(*APIError)
+	testEquals(t, ok, true)
+	testEquals(t, apiErr.Is(ErrNotFound), true)
+
+	col, err := d.CreateColumn(ctx, "my-service", Column{KeyName: "duration_ms", Type: ColumnTypeFloat})
+	testOK(t, err)
+	testEquals(t, col.ID, "c2")
+
+	col, err = d.SetColumnType(ctx, "my-service", "status", ColumnTypeInteger)
+	testOK(t, err)
+	testEquals(t, col.Type, ColumnTypeInteger)
+	testEquals(t, lastPut.KeyName, "status")
+
+	col, err = d.SetColumnDescription(ctx, "my-service", "status", "HTTP status code")
+	testOK(t, err)
+	testEquals(t, col.Description, "HTTP status code")
+	testEquals(t, col.Type, ColumnTypeInteger)
+
+	col, err = d.SetColumnHidden(ctx, "my-service", "status", true)
+	testOK(t, err)
+	testEquals(t, col.Hidden, true)
+
+	_, err = d.SetColumnHidden(ctx, "my-service", "nope", true)
+	notFound, ok := err.(*ColumnNotFoundError)
+	testEquals(t, ok, true)
+	testEquals(t, notFound.Is(ErrNotFound), true)
+	testEquals(t, *notFound, ColumnNotFoundError{Dataset: "my-service", KeyName: "nope"})
+	testEquals(t, err.Error(), `column "nope" in dataset "my-service": not found`)
+
+	cols, err := d.ListColumns(ctx, "my-service")
+	testOK(t, err)
+	testEquals(t, len(cols), 2)
+}
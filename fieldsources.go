@@ -0,0 +1,154 @@
+package libhoney
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The FieldsFrom functions return functions for AddFunc (on an Event, Builder,
+// PrefixedBuilder or EventNamespace) that stream fields from common key/value
+// sources. Each takes an optional allowlist of names to include; with no
+// allowlist every name is included. An allowlist entry ending in "*" matches
+// every name with that prefix, so "HONEYCOMB_*" allows all of the environment
+// variables starting "HONEYCOMB_".
+
+// allowlist matches names against exact entries and "prefix*" entries.
+type allowlist struct {
+	names    map[string]bool
+	prefixes []string
+}
+
+func newAllowlist(allow []string, canonical func(string) string) *allowlist {
+	if len(allow) == 0 {
+		return nil
+	}
+	a := &allowlist{names: make(map[string]bool, len(allow))}
+	for _, name := range allow {
+		if strings.HasSuffix(name, "*") {
+			a.prefixes = append(a.prefixes, canonical(strings.TrimSuffix(name, "*")))
+			continue
+		}
+		a.names[canonical(name)] = true
+	}
+	return a
+}
+
+// allows reports whether name, already in canonical form, is allowed. A nil
+// allowlist allows everything.
+func (a *allowlist) allows(name string) bool {
+	if a == nil || a.names[name] {
+		return true
+	}
+	for _, p := range a.prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func identity(s string) string { return s }
+
+// fieldsFromSorted returns an AddFunc function that yields the allowed keys in
+// sorted order, with values from value.
+func fieldsFromSorted(keys []string, allow *allowlist, value func(string) interface{}) func() (string, interface{}, error) {
+	sort.Strings(keys)
+	i := 0
+	return func() (string, interface{}, error) {
+		for i < len(keys) {
+			key := keys[i]
+			i++
+			if allow.allows(key) {
+				return key, value(key), nil
+			}
+		}
+		return "", nil, io.EOF
+	}
+}
+
+// FieldsFromScanner returns an AddFunc function that reads "key=value" lines
+// from s, adding each as a string field. Surrounding whitespace is trimmed from
+// keys and values, and blank lines and lines starting with "#" are skipped. A
+// line without "=" or an error from s stops AddFunc with an error.
+func FieldsFromScanner(s *bufio.Scanner, allow ...string) func() (string, interface{}, error) {
+	a := newAllowlist(allow, identity)
+	line := 0
+	return func() (string, interface{}, error) {
+		for s.Scan() {
+			line++
+			text := strings.TrimSpace(s.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			idx := strings.Index(text, "=")
+			if idx == -1 {
+				return "", nil, fmt.Errorf("line %d: expected key=value, got %q", line, text)
+			}
+			key := strings.TrimSpace(text[:idx])
+			if a.allows(key) {
+				return key, strings.TrimSpace(text[idx+1:]), nil
+			}
+		}
+		if err := s.Err(); err != nil {
+			return "", nil, err
+		}
+		return "", nil, io.EOF
+	}
+}
+
+// FieldsFromURLValues returns an AddFunc function that adds each allowed key
+// in v as a string field. Keys with several values are joined with ",".
+func FieldsFromURLValues(v url.Values, allow ...string) func() (string, interface{}, error) {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	return fieldsFromSorted(keys, newAllowlist(allow, identity), func(k string) interface{} {
+		return strings.Join(v[k], ",")
+	})
+}
+
+// FieldsFromHeader returns an AddFunc function that adds each allowed header in
+// h as a string field named by its canonical key (e.g. "User-Agent"). The
+// allowlist is case-insensitive. Headers with several values are joined with
+// ",", as HTTP allows.
+func FieldsFromHeader(h http.Header, allow ...string) func() (string, interface{}, error) {
+	// headers set directly on the map may not be in canonical form
+	values := make(map[string][]string, len(h))
+	for k, v := range h {
+		ck := http.CanonicalHeaderKey(k)
+		values[ck] = append(values[ck], v...)
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	return fieldsFromSorted(keys, newAllowlist(allow, http.CanonicalHeaderKey), func(k string) interface{} {
+		return strings.Join(values[k], ",")
+	})
+}
+
+// FieldsFromEnviron returns an AddFunc function that adds each allowed
+// environment variable (as found by os.Environ when it is called) as a string
+// field. Take care not to send secrets: an allowlist is strongly recommended.
+func FieldsFromEnviron(allow ...string) func() (string, interface{}, error) {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if idx := strings.Index(kv, "="); idx > 0 {
+			env[kv[:idx]] = kv[idx+1:]
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	return fieldsFromSorted(keys, newAllowlist(allow, identity), func(k string) interface{} {
+		return env[k]
+	})
+}
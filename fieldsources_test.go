@@ -0,0 +1,116 @@
+package libhoney
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAddFuncErrors(t *testing.T) {
+	resetPackageVars()
+	ev := NewEvent()
+	failure := errors.New("read failed")
+	i := 0
+	err := ev.AddFunc(func() (string, interface{}, error) {
+		if i > 0 {
+			return "", nil, failure
+		}
+		i++
+		return "a", 1, nil
+	})
+	testEquals(t, err, failure)
+	// fields added before the error are kept
+	testEquals(t, ev.data["a"], 1)
+
+	testOK(t, ev.AddFunc(func() (string, interface{}, error) {
+		return "", nil, io.EOF
+	}))
+}
+
+func TestFieldsFromScanner(t *testing.T) {
+	resetPackageVars()
+	input := "a=1\n\n# comment\n b = two words \nc=x=y\nsecret=shh\n"
+	ev := NewEvent()
+	testOK(t, ev.AddFunc(FieldsFromScanner(bufio.NewScanner(strings.NewReader(input)))))
+	testEquals(t, ev.Fields(), map[string]interface{}{
+		"a":      "1",
+		"b":      "two words",
+		"c":      "x=y",
+		"secret": "shh",
+	})
+
+	ev = NewEvent()
+	testOK(t, ev.AddFunc(FieldsFromScanner(bufio.NewScanner(strings.NewReader(input)), "a", "b")))
+	testEquals(t, ev.Fields(), map[string]interface{}{"a": "1", "b": "two words"})
+
+	ev = NewEvent()
+	err := ev.AddFunc(FieldsFromScanner(bufio.NewScanner(strings.NewReader("a=1\nbad\nc=3"))))
+	testEquals(t, err.Error(), `line 2: expected key=value, got "bad"`)
+	testEquals(t, ev.Fields(), map[string]interface{}{"a": "1"})
+
+	// errors from the scanner itself are returned
+	s := bufio.NewScanner(strings.NewReader("a=" + strings.Repeat("x", 100)))
+	s.Buffer(nil, 10)
+	testEquals(t, NewEvent().AddFunc(FieldsFromScanner(s)), bufio.ErrTooLong)
+}
+
+func TestFieldsFromURLValues(t *testing.T) {
+	resetPackageVars()
+	v := url.Values{
+		"q":      {"honeycomb"},
+		"tag":    {"a", "b"},
+		"utm_id": {"1"},
+		"token":  {"secret"},
+	}
+	ev := NewEvent()
+	testOK(t, ev.Namespace("query").AddFunc(FieldsFromURLValues(v, "q", "tag", "utm_*")))
+	testEquals(t, ev.Fields(), map[string]interface{}{
+		"query.q":      "honeycomb",
+		"query.tag":    "a,b",
+		"query.utm_id": "1",
+	})
+}
+
+func TestFieldsFromHeader(t *testing.T) {
+	resetPackageVars()
+	h := http.Header{}
+	h.Set("User-Agent", "test")
+	h.Add("Accept", "text/plain")
+	h.Add("Accept", "application/json")
+	h.Set("X-Request-Id", "abc")
+	h.Set("Authorization", "secret")
+	h["x-lower"] = []string{"raw"}
+
+	ev := NewEvent()
+	testOK(t, ev.AddFunc(FieldsFromHeader(h, "user-agent", "ACCEPT", "x-*")))
+	testEquals(t, ev.Fields(), map[string]interface{}{
+		"User-Agent":   "test",
+		"Accept":       "text/plain,application/json",
+		"X-Request-Id": "abc",
+		"X-Lower":      "raw",
+	})
+
+	ev = NewEvent()
+	testOK(t, ev.AddFunc(FieldsFromHeader(h)))
+	testEquals(t, len(ev.Fields()), 5)
+}
+
+func TestFieldsFromEnviron(t *testing.T) {
+	resetPackageVars()
+	os.Setenv("LIBHONEY_TEST_A", "a=1")
+	os.Setenv("LIBHONEY_TEST_B", "")
+	defer os.Unsetenv("LIBHONEY_TEST_A")
+	defer os.Unsetenv("LIBHONEY_TEST_B")
+
+	b := NewBuilder()
+	testOK(t, b.WithPrefix("env.").AddFunc(FieldsFromEnviron("LIBHONEY_TEST_*", "LIBHONEY_MISSING")))
+	testEquals(t, b.Fields(), map[string]interface{}{
+		"env.LIBHONEY_TEST_A": "a=1",
+		"env.LIBHONEY_TEST_B": "",
+	})
+}
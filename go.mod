@@ -16,4 +16,5 @@ require (
 	github.com/vmihailenco/msgpack/v4 v4.3.12
 	golang.org/x/net v0.0.0-20200324143707-d3edc9973b7e // indirect
 	gopkg.in/alexcesaro/statsd.v2 v2.0.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
@@ -0,0 +1,89 @@
+// Package logging holds the logging helpers shared by libhoney and its
+// transmission package. Both packages' LeveledLogger interfaces embed the one
+// here, and their Logger interfaces satisfy it.
+package logging
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logger is anything with a fmt.Printf style method.
+type Logger interface {
+	Printf(msg string, args ...interface{})
+}
+
+// LeveledLogger is a Logger with a method per severity, taking context as
+// alternating key/value pairs.
+type LeveledLogger interface {
+	Logger
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// Debug, Info, Warn and Error send msg to l at the matching level, falling
+// back to Printf with the key/value pairs appended if l isn't a
+// LeveledLogger.
+func Debug(l Logger, msg string, keyvals ...interface{}) {
+	if ll, ok := l.(LeveledLogger); ok {
+		ll.Debug(msg, keyvals...)
+		return
+	}
+	l.Printf("%s", Message{msg, keyvals})
+}
+
+func Info(l Logger, msg string, keyvals ...interface{}) {
+	if ll, ok := l.(LeveledLogger); ok {
+		ll.Info(msg, keyvals...)
+		return
+	}
+	l.Printf("%s", Message{msg, keyvals})
+}
+
+func Warn(l Logger, msg string, keyvals ...interface{}) {
+	if ll, ok := l.(LeveledLogger); ok {
+		ll.Warn(msg, keyvals...)
+		return
+	}
+	l.Printf("%s", Message{msg, keyvals})
+}
+
+func Error(l Logger, msg string, keyvals ...interface{}) {
+	if ll, ok := l.(LeveledLogger); ok {
+		ll.Error(msg, keyvals...)
+		return
+	}
+	l.Printf("%s", Message{msg, keyvals})
+}
+
+// Message is a message and its key/value pairs, formatted only when a logger
+// actually prints it, so that loggers that discard messages never pay for
+// formatting or read the values.
+type Message struct {
+	Msg     string
+	Keyvals []interface{}
+}
+
+func (m Message) String() string {
+	return Format(m.Msg, m.Keyvals)
+}
+
+// Format renders msg followed by space-separated key=value pairs. An odd
+// trailing key is paired with "(MISSING)".
+func Format(msg string, keyvals []interface{}) string {
+	if len(keyvals) == 0 {
+		return msg
+	}
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var val interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+		fmt.Fprintf(&sb, " %v=%+v", keyvals[i], val)
+	}
+	return sb.String()
+}
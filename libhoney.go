@@ -5,21 +5,22 @@
 package libhoney
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"math/rand"
 	"net/http"
-	"net/url"
-	"path"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/honeycombio/libhoney-go/internal/jsonstr"
+	"github.com/honeycombio/libhoney-go/internal/logging"
 	"github.com/honeycombio/libhoney-go/transmission"
 	statsd "gopkg.in/alexcesaro/statsd.v2"
 )
@@ -132,6 +133,23 @@ type Config struct {
 	MaxConcurrentBatches uint          // how many batches can be inflight simultaneously. Overrides DefaultMaxConcurrentBatches.
 	PendingWorkCapacity  uint          // how many events to allow to pile up. Overrides DefaultPendingWorkCapacity
 
+	// DisableCompression turns off zstd compression of batches sent by the
+	// default Honeycomb transmission.
+	DisableCompression bool
+
+	// EnableMsgpackEncoding makes the default Honeycomb transmission send
+	// batches encoded as msgpack instead of JSON.
+	EnableMsgpackEncoding bool
+
+	// KeyPolicy sets what the default Honeycomb transmission does with field
+	// names containing quotes, backslashes, control characters or invalid
+	// UTF-8. The default is to escape them.
+	KeyPolicy transmission.KeyPolicy
+
+	// PoolEvents recycles events once they have been sent. See
+	// ClientConfig.PoolEvents.
+	PoolEvents bool
+
 	// Transport is deprecated and should not be used. To set the HTTP Transport
 	// set the Transport elements on the Transmission Sender instead.
 	Transport http.RoundTripper
@@ -141,6 +159,12 @@ type Config struct {
 	// Intended for human consumption during development to understand what the
 	// SDK is doing and diagnose trouble emitting events.
 	Logger Logger
+
+	// Metrics receives the default Honeycomb transmission's internal counters
+	// and gauges. Defaults to a muted statsd client. Use
+	// transmission.NewExpvarMetrics or transmission.NewPrometheusMetrics to
+	// export them. Ignored if Transmission or Output is set.
+	Metrics transmission.Metrics
 }
 
 // Init is called on app initialization and passed a Config struct, which
@@ -152,8 +176,14 @@ type Config struct {
 // Dataset, SampleRate, and APIHost can all be overridden on a per-Builder or
 // per-Event basis.
 //
+// Init returns the error from conf.Validate, without setting anything up, if
+// conf is invalid.
+//
 // Make sure to call Close() to flush buffers.
 func Init(conf Config) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
 
 	// populate a client config to spin up the default package-level Client
 	clientConf := ClientConfig{}
@@ -170,6 +200,7 @@ func Init(conf Config) error {
 	clientConf.Dataset = conf.Dataset
 	clientConf.SampleRate = conf.SampleRate
 	clientConf.APIHost = conf.APIHost
+	clientConf.PoolEvents = conf.PoolEvents
 
 	// set up default Logger because we're going to use it for the transmission
 	if conf.Logger == nil {
@@ -177,6 +208,10 @@ func Init(conf Config) error {
 	}
 	clientConf.Logger = conf.Logger
 
+	if conf.Metrics == nil {
+		conf.Metrics = sd
+	}
+
 	// set up defaults for the Transmission
 	if conf.MaxBatchSize == 0 {
 		conf.MaxBatchSize = DefaultMaxBatchSize
@@ -205,16 +240,19 @@ func Init(conf Config) error {
 		}
 	default:
 		t = &transmission.Honeycomb{
-			MaxBatchSize:         conf.MaxBatchSize,
-			BatchTimeout:         conf.SendFrequency,
-			MaxConcurrentBatches: conf.MaxConcurrentBatches,
-			PendingWorkCapacity:  conf.PendingWorkCapacity,
-			BlockOnSend:          conf.BlockOnSend,
-			BlockOnResponse:      conf.BlockOnResponse,
-			Transport:            conf.Transport,
-			UserAgentAddition:    UserAgentAddition,
-			Logger:               clientConf.Logger,
-			Metrics:              sd,
+			MaxBatchSize:          conf.MaxBatchSize,
+			BatchTimeout:          conf.SendFrequency,
+			MaxConcurrentBatches:  conf.MaxConcurrentBatches,
+			PendingWorkCapacity:   conf.PendingWorkCapacity,
+			BlockOnSend:           conf.BlockOnSend,
+			BlockOnResponse:       conf.BlockOnResponse,
+			DisableCompression:    conf.DisableCompression,
+			EnableMsgpackEncoding: conf.EnableMsgpackEncoding,
+			KeyPolicy:             conf.KeyPolicy,
+			Transport:             conf.Transport,
+			UserAgentAddition:     UserAgentAddition,
+			Logger:                clientConf.Logger,
+			Metrics:               conf.Metrics,
 		}
 	}
 	clientConf.Transmission = t
@@ -278,50 +316,30 @@ func VerifyWriteKey(config Config) (team string, err error) {
 
 // VerifyAPIKey calls out to the Honeycomb API to validate the API key so we can
 // exit immediately if desired instead of happily sending events that are all
-// rejected.
+// rejected. It returns the slug of the team the key belongs to. Use
+// VerifyAPIKeyContext for more detail and control over the request.
+//
+// The key is checked against the API's /1/auth endpoint (it used to be
+// /1/team_slug), so proxies and allowlists in front of the API must let that
+// path through. Errors keep their original messages, and also match
+// ErrInvalidAPIKey or *APIError as VerifyAPIKeyContext's do.
 func VerifyAPIKey(config Config) (team string, err error) {
 	dc.ensureLogger()
-	defer func() { dc.logger.Printf("verify write key got back %s with err=%s", team, err) }()
-	if config.APIKey == "" {
-		if config.WriteKey == "" {
-			return team, errors.New("config.APIKey and config.WriteKey are both empty; can't verify empty key")
+	defer func() {
+		if err != nil {
+			logging.Error(dc.logger, "failed to verify API key", "err", err)
+		} else {
+			logging.Info(dc.logger, "verified API key", "team", team)
 		}
-		config.APIKey = config.WriteKey
-	}
-	if config.APIHost == "" {
-		config.APIHost = defaultAPIHost
-	}
-	u, err := url.Parse(config.APIHost)
-	if err != nil {
-		return team, fmt.Errorf("Error parsing API URL: %s", err)
-	}
-	u.Path = path.Join(u.Path, "1", "team_slug")
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return team, err
+	}()
+	if config.APIKey == "" && config.WriteKey == "" {
+		return team, errors.New("config.APIKey and config.WriteKey are both empty; can't verify empty key")
 	}
-	req.Header.Set("User-Agent", UserAgentAddition)
-	req.Header.Add("X-Honeycomb-Team", config.APIKey)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	info, err := VerifyAPIKeyContext(context.Background(), config)
 	if err != nil {
-		return team, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		return team, errors.New("Write key provided is invalid")
-	}
-	body, _ := ioutil.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		return team, fmt.Errorf(`Abnormal non-200 response verifying Honeycomb write key: %d
-Response body: %s`, resp.StatusCode, string(body))
-	}
-	ret := map[string]string{}
-	if err := json.Unmarshal(body, &ret); err != nil {
-		return team, err
+		return team, legacyVerifyErr(err)
 	}
-
-	return ret["team_slug"], nil
+	return info.TeamSlug, nil
 }
 
 // Response is deprecated; please use transmission.Response instead.
@@ -354,11 +372,20 @@ type Event struct {
 	// client is the Client to use to send events generated from this builder
 	client *Client
 
+	// sendFields are the builder's dynamic fields to evaluate at send time
+	sendFields []dynamicField
+
 	// sent is a bool indicating whether the event has been sent.  Once it's
 	// been sent, all changes to the event should be ignored - any calls to Add
 	// should just return immediately taking no action.
 	sent     bool
 	sendLock sync.Mutex
+
+	// pooled events are recycled once the transmission is done with them;
+	// tx and releaseTx are reused for each send
+	pooled    bool
+	tx        transmission.Event
+	releaseTx func(*transmission.Event)
 }
 
 // Builder is used to create templates for new events, specifying default fields
@@ -387,6 +414,62 @@ type Builder struct {
 type fieldHolder struct {
 	data marshallableMap
 	lock sync.RWMutex
+
+	// shared is set when data may also be referenced by other events or
+	// builders, in which case it must be copied before it is changed. It is
+	// accessed atomically since it is set while holding only the read lock.
+	shared int32
+	// spare, if set, is an empty map to copy data into instead of allocating
+	spare marshallableMap
+
+	// schema, if set, is applied to every field added
+	schema *fieldSchema
+}
+
+// share marks data as shared and returns it, so that another event or builder
+// can inherit the fields without copying them. The caller must hold f.lock,
+// for reading at least.
+func (f *fieldHolder) share() marshallableMap {
+	atomic.StoreInt32(&f.shared, 1)
+	return f.data
+}
+
+// own makes data safe to change, copying it first if it is shared. The caller
+// must hold f.lock for writing.
+func (f *fieldHolder) own() {
+	if atomic.LoadInt32(&f.shared) == 0 {
+		return
+	}
+	data := f.spare
+	if data == nil {
+		data = make(marshallableMap, len(f.data)+1)
+	}
+	for k, v := range f.data {
+		data[k] = v
+	}
+	f.data = data
+	f.spare = nil
+	atomic.StoreInt32(&f.shared, 0)
+}
+
+// set stores val under key, subject to the schema if there is one. Any
+// schema violation is added to violations, to be reported once the caller
+// releases f.lock, which it must hold.
+func (f *fieldHolder) set(key string, val interface{}, violations *schemaViolations) {
+	if f.schema != nil {
+		var ok bool
+		var v *SchemaViolation
+		val, ok, v = f.schema.check(key, val)
+		if v != nil {
+			violations.schema = f.schema
+			violations.found = append(violations.found, v)
+		}
+		if !ok {
+			return
+		}
+	}
+	f.own()
+	f.data[key] = val
 }
 
 // Wrapper type for custom JSON serialization: individual values that can't be
@@ -404,7 +487,7 @@ func (m marshallableMap) MarshalJSON() ([]byte, error) {
 		i++
 	}
 	sort.Strings(keys)
-	out := bytes.NewBufferString("{")
+	out := []byte{'{'}
 
 	first := true
 	for _, k := range keys {
@@ -413,18 +496,17 @@ func (m marshallableMap) MarshalJSON() ([]byte, error) {
 			if first {
 				first = false
 			} else {
-				out.WriteByte(',')
+				out = append(out, ',')
 			}
 
-			out.WriteByte('"')
-			out.Write([]byte(k))
-			out.WriteByte('"')
-			out.WriteByte(':')
-			out.Write(b)
+			// escape keys exactly as the transmission encoder does
+			out = jsonstr.Append(out, k)
+			out = append(out, ':')
+			out = append(out, b...)
 		}
 	}
-	out.WriteByte('}')
-	return out.Bytes(), nil
+	out = append(out, '}')
+	return out, nil
 }
 
 func maybeMarshalValue(v interface{}) ([]byte, bool) {
@@ -448,6 +530,9 @@ func maybeMarshalValue(v interface{}) ([]byte, bool) {
 type dynamicField struct {
 	name string
 	fn   func() interface{}
+	// sendFn, if set, is used instead of fn and is evaluated when the event
+	// is sent rather than when it is created
+	sendFn func(context.Context, *Event) interface{}
 }
 
 // Close waits for all in-flight messages to be sent. You should
@@ -481,7 +566,7 @@ func SendNow(data interface{}) error {
 		return err
 	}
 	err := ev.Send()
-	dc.logger.Printf("SendNow enqueued event, err=%v", err)
+	logging.Debug(dc.logger, "SendNow enqueued event", "err", err)
 	return err
 }
 
@@ -511,6 +596,12 @@ func TxResponses() chan transmission.Response {
 	return dc.TxResponses()
 }
 
+// Stats returns a snapshot of the package-level client's transmission
+// counters. See Client.Stats.
+func Stats() transmission.Stats {
+	return dc.Stats()
+}
+
 // AddDynamicField takes a field name and a function that will generate values
 // for that metric. The function is called once every time a NewEvent() is
 // created and added as a field (with name as the key) to the newly created
@@ -519,6 +610,18 @@ func AddDynamicField(name string, fn func() interface{}) error {
 	return dc.AddDynamicField(name, fn)
 }
 
+// AddDynamicFieldFunc adds a dynamic field to the global scope whose value is
+// computed when each event is sent. See Builder.AddDynamicFieldFunc.
+func AddDynamicFieldFunc(name string, fn func(context.Context, *Event) interface{}) error {
+	return dc.AddDynamicFieldFunc(name, fn)
+}
+
+// RemoveDynamicField removes the dynamic fields called name from the global
+// scope. See Builder.RemoveDynamicField.
+func RemoveDynamicField(name string) bool {
+	return dc.RemoveDynamicField(name)
+}
+
 // AddField adds a Field to the global scope. This metric will be inherited by
 // all builders and events.
 func AddField(name string, val interface{}) {
@@ -548,22 +651,29 @@ func NewBuilder() *Builder {
 // called. Note that if you add a value that cannot be serialized to JSON (eg a
 // function or channel), the event will fail to send.
 func (f *fieldHolder) AddField(key string, val interface{}) {
+	var violations schemaViolations
 	f.lock.Lock()
-	defer f.lock.Unlock()
-	f.data[key] = val
+	f.set(key, val, &violations)
+	f.lock.Unlock()
+	violations.report()
 }
 
 // Add adds a complex data type to the event or builder on which it's called.
 // For structs, it adds each exported field. For maps, it adds each key/value.
 // Add will error on all other types.
 func (f *fieldHolder) Add(data interface{}) error {
+	return f.addPrefixed("", data)
+}
+
+// addPrefixed is Add, with prefix prepended to the name of every field added.
+func (f *fieldHolder) addPrefixed(prefix string, data interface{}) error {
 	switch reflect.TypeOf(data).Kind() {
 	case reflect.Struct:
-		return f.addStruct(data)
+		return f.addStruct(prefix, data)
 	case reflect.Map:
-		return f.addMap(data)
+		return f.addMap(prefix, data)
 	case reflect.Ptr:
-		return f.Add(reflect.ValueOf(data).Elem().Interface())
+		return f.addPrefixed(prefix, reflect.ValueOf(data).Elem().Interface())
 	}
 	return fmt.Errorf(
 		"Couldn't add type %s content %+v",
@@ -571,7 +681,9 @@ func (f *fieldHolder) Add(data interface{}) error {
 	)
 }
 
-func (f *fieldHolder) addStruct(s interface{}) error {
+func (f *fieldHolder) addStruct(prefix string, s interface{}) error {
+	var violations schemaViolations
+	defer violations.report()
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
@@ -607,12 +719,14 @@ func (f *fieldHolder) addStruct(s interface{}) error {
 			fName = fieldInfo.Name
 		}
 
-		f.data[fName] = sVal.Field(i).Interface()
+		f.set(prefix+fName, sVal.Field(i).Interface(), &violations)
 	}
 	return nil
 }
 
-func (f *fieldHolder) addMap(m interface{}) error {
+func (f *fieldHolder) addMap(prefix string, m interface{}) error {
+	var violations schemaViolations
+	defer violations.report()
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
@@ -632,31 +746,81 @@ func (f *fieldHolder) addMap(m interface{}) error {
 		default:
 			return fmt.Errorf("failed to add map: key type %s unaccepted", key.Type().Kind())
 		}
-		f.data[keyStr] = mVal.MapIndex(key).Interface()
+		f.set(prefix+keyStr, mVal.MapIndex(key).Interface(), &violations)
 	}
 	return nil
 }
 
 // AddFunc takes a function and runs it repeatedly, adding the return values
 // as fields.
-// The function should return error when it has exhausted its values
+// The function should return io.EOF when it has exhausted its values. Any
+// other error stops AddFunc and is returned; fields added before the error
+// are kept. See FieldsFromScanner, FieldsFromURLValues, FieldsFromHeader and
+// FieldsFromEnviron for ready-made functions.
 func (f *fieldHolder) AddFunc(fn func() (string, interface{}, error)) error {
 	for {
 		key, rawVal, err := fn()
-		if err != nil {
+		if err == io.EOF {
 			// fn is done giving us data
-			break
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 		f.AddField(key, rawVal)
 	}
-	return nil
 }
 
-// Fields returns a reference to the map of fields that have been added to an
-// event. Caution: it is not safe to manipulate the returned map concurrently
-// with calls to AddField, Add or AddFunc.
+// Fields returns a copy of the fields that have been added to the event or
+// builder on which it is called. Changing the returned map has no effect on
+// the event or builder.
 func (f *fieldHolder) Fields() map[string]interface{} {
-	return f.data
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	fields := make(map[string]interface{}, len(f.data))
+	for k, v := range f.data {
+		fields[k] = v
+	}
+	return fields
+}
+
+// logFields formats the fields of f for a log message. The fields are read,
+// under the lock, only if a logger prints the message.
+type logFields struct {
+	f *fieldHolder
+}
+
+func (l logFields) String() string {
+	return fmt.Sprint(l.f.Fields())
+}
+
+// RemoveField removes the field called key from the event or builder on which
+// it is called, if it is there.
+func (f *fieldHolder) RemoveField(key string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, ok := f.data[key]; ok {
+		f.own()
+		delete(f.data, key)
+	}
+}
+
+// HasField reports whether the event or builder on which it is called has a
+// field called key.
+func (f *fieldHolder) HasField(key string) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	_, ok := f.data[key]
+	return ok
+}
+
+// GetField returns the value of the field called key and whether the event or
+// builder on which it is called has that field.
+func (f *fieldHolder) GetField(key string) (interface{}, bool) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	val, ok := f.data[key]
+	return val, ok
 }
 
 // mask the add functions on an event so that we can test the sent lock and noop
@@ -684,17 +848,22 @@ func (e *Event) AddField(key string, val interface{}) {
 // Adds to an event that happen after it has been sent will return without
 // having any effect.
 func (e *Event) Add(data interface{}) error {
+	return e.addPrefixed("", data)
+}
+
+func (e *Event) addPrefixed(prefix string, data interface{}) error {
 	e.sendLock.Lock()
 	defer e.sendLock.Unlock()
 	if e.sent == true {
 		return nil
 	}
-	return e.fieldHolder.Add(data)
+	return e.fieldHolder.addPrefixed(prefix, data)
 }
 
 // AddFunc takes a function and runs it repeatedly, adding the return values
 // as fields.
-// The function should return error when it has exhausted its values
+// The function should return io.EOF when it has exhausted its values. Any
+// other error stops AddFunc and is returned.
 //
 // Adds to an event that happen after it has been sent will return without
 // having any effect.
@@ -707,6 +876,19 @@ func (e *Event) AddFunc(fn func() (string, interface{}, error)) error {
 	return e.fieldHolder.AddFunc(fn)
 }
 
+// RemoveField removes the field called key from the event, if it is there.
+//
+// Removes from an event that happen after it has been sent will return without
+// having any effect.
+func (e *Event) RemoveField(key string) {
+	e.sendLock.Lock()
+	defer e.sendLock.Unlock()
+	if e.sent == true {
+		return
+	}
+	e.fieldHolder.RemoveField(key)
+}
+
 // Send dispatches the event to be sent to Honeycomb, sampling if necessary.
 //
 // If you have sampling enabled
@@ -723,17 +905,28 @@ func (e *Event) AddFunc(fn func() (string, interface{}, error)) error {
 // Once you Send an event, any addition calls to add data to that event will
 // return without doing anything. Once the event is sent, it becomes immutable.
 func (e *Event) Send() error {
+	return e.SendCtx(context.Background())
+}
+
+// SendCtx is Send, passing ctx to any dynamic fields added to the event's
+// builder with AddDynamicFieldFunc.
+func (e *Event) SendCtx(ctx context.Context) error {
 	if e.client == nil {
 		e.client = &Client{}
 	}
 	e.client.ensureLogger()
 	if shouldDrop(e.SampleRate) {
-		e.client.logger.Printf("dropping event due to sampling")
+		if !isNullLogger(e.client.logger) {
+			logging.Debug(e.client.logger, "dropping event due to sampling", "sample_rate", e.SampleRate)
+		}
 		sd.Increment("sampled")
 		e.client.sendDroppedResponse(e, "event dropped due to sampling")
+		if e.pooled {
+			releaseEvent(e)
+		}
 		return nil
 	}
-	return e.SendPresampled()
+	return e.SendPresampledCtx(ctx)
 }
 
 // SendPresampled dispatches the event to be sent to Honeycomb.
@@ -750,19 +943,44 @@ func (e *Event) Send() error {
 //
 // Once you Send an event, any addition calls to add data to that event will
 // return without doing anything. Once the event is sent, it becomes immutable.
-func (e *Event) SendPresampled() (err error) {
+func (e *Event) SendPresampled() error {
+	return e.SendPresampledCtx(context.Background())
+}
+
+// SendPresampledCtx is SendPresampled, passing ctx to any dynamic fields added
+// to the event's builder with AddDynamicFieldFunc.
+func (e *Event) SendPresampledCtx(ctx context.Context) error {
 	if e.client == nil {
 		e.client = &Client{}
 	}
 	e.client.ensureLogger()
-	defer func() {
-		if err != nil {
-			e.client.logger.Printf("Failed to send event. err: %s, event: %+v", err, e)
-		} else {
-			e.client.logger.Printf("Send enqueued event: %+v", e)
-		}
-	}()
 
+	// Send-time fields may read the event, so evaluate them before locking it.
+	e.addSendFields(ctx)
+
+	txEvent, err := e.prepareSend()
+	if err != nil {
+		logging.Error(e.client.logger, "failed to send event", "err", err,
+			"dataset", e.Dataset, "fields", logFields{&e.fieldHolder})
+		return err
+	}
+	// skip building the log arguments on this hot path if nothing will log them
+	if !isNullLogger(e.client.logger) {
+		logging.Debug(e.client.logger, "send enqueued event",
+			"dataset", e.Dataset, "fields", logFields{&e.fieldHolder})
+	}
+
+	// A pooled event may be recycled as soon as it is handed over, so this
+	// must be the last use of e.
+	client := e.client
+	client.ensureTransmission()
+	client.transmission.Add(txEvent)
+	return nil
+}
+
+// prepareSend checks that the event can be sent, marks it as sent and returns
+// the transmission.Event to send.
+func (e *Event) prepareSend() (*transmission.Event, error) {
 	// Lock the sent bool before taking the event lock, to match the order in
 	// the Add methods.
 	e.sendLock.Lock()
@@ -771,7 +989,7 @@ func (e *Event) SendPresampled() (err error) {
 	e.fieldHolder.lock.RLock()
 	defer e.fieldHolder.lock.RUnlock()
 	if len(e.data) == 0 {
-		return errors.New("No metrics added to event. Won't send empty event.")
+		return nil, errors.New("No metrics added to event. Won't send empty event.")
 	}
 
 	// if client.transmission is transmission.Honeycomb or a pointer to same,
@@ -784,21 +1002,24 @@ func (e *Event) SendPresampled() (err error) {
 	isMockSender := strings.HasSuffix(senderType, "transmission.MockSender")
 	if isHoneycombSender || isMockSender {
 		if e.APIHost == "" {
-			return errors.New("No APIHost for Honeycomb. Can't send to the Great Unknown.")
+			return nil, errors.New("No APIHost for Honeycomb. Can't send to the Great Unknown.")
 		}
 		if e.WriteKey == "" {
-			return errors.New("No WriteKey specified. Can't send event.")
+			return nil, errors.New("No WriteKey specified. Can't send event.")
 		}
 	}
 	if e.Dataset == "" {
-		return errors.New("No Dataset for Honeycomb. Can't send datasetless.")
+		return nil, errors.New("No Dataset for Honeycomb. Can't send datasetless.")
 	}
 
 	// Mark the event as sent, no more field changes will be applied.
 	e.sent = true
 
-	e.client.ensureTransmission()
-	txEvent := &transmission.Event{
+	txEvent := &e.tx
+	if !e.pooled {
+		txEvent = &transmission.Event{}
+	}
+	*txEvent = transmission.Event{
 		APIHost:    e.APIHost,
 		APIKey:     e.WriteKey,
 		Dataset:    e.Dataset,
@@ -806,9 +1027,26 @@ func (e *Event) SendPresampled() (err error) {
 		Timestamp:  e.Timestamp,
 		Metadata:   e.Metadata,
 		Data:       e.data,
+		Release:    e.releaseTx,
+	}
+	return txEvent, nil
+}
+
+// addSendFields evaluates the send-time dynamic fields inherited from the
+// event's builder and adds their values to the event.
+func (e *Event) addSendFields(ctx context.Context) {
+	e.sendLock.Lock()
+	sent, fields := e.sent, e.sendFields
+	e.sendLock.Unlock()
+	if sent || len(fields) == 0 {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for _, f := range fields {
+		e.AddField(f.name, f.sendFn(ctx, e))
 	}
-	e.client.transmission.Add(txEvent)
-	return nil
 }
 
 // returns true if the sample should be dropped
@@ -833,6 +1071,40 @@ func (b *Builder) AddDynamicField(name string, fn func() interface{}) error {
 	return nil
 }
 
+// AddDynamicFieldFunc adds a dynamic field to the builder whose value is
+// computed when each event created from this builder is sent, rather than when
+// it is created. fn is passed the context given to SendCtx (or
+// context.Background() for Send) and the event itself, so it can compute
+// values such as the event's total duration or per-request data. fn must not
+// send the event. If fn adds fields to the event they are sent too.
+func (b *Builder) AddDynamicFieldFunc(name string, fn func(context.Context, *Event) interface{}) error {
+	b.dynFieldsLock.Lock()
+	defer b.dynFieldsLock.Unlock()
+	b.dynFields = append(b.dynFields, dynamicField{
+		name:   name,
+		sendFn: fn,
+	})
+	return nil
+}
+
+// RemoveDynamicField removes every dynamic field called name, whether added
+// with AddDynamicField or AddDynamicFieldFunc, from the builder. It reports
+// whether any were removed. Events already created from the builder and
+// builders cloned from it are not affected.
+func (b *Builder) RemoveDynamicField(name string) bool {
+	b.dynFieldsLock.Lock()
+	defer b.dynFieldsLock.Unlock()
+	kept := make([]dynamicField, 0, len(b.dynFields))
+	for _, dynField := range b.dynFields {
+		if dynField.name != name {
+			kept = append(kept, dynField)
+		}
+	}
+	removed := len(kept) != len(b.dynFields)
+	b.dynFields = kept
+	return removed
+}
+
 // SendNow is deprecated and may be removed in a future major release.
 // Contrary to its name, SendNow does not block and send data
 // immediately, but only enqueues to be sent asynchronously.
@@ -852,32 +1124,53 @@ func (b *Builder) SendNow(data interface{}) error {
 // NewEvent creates a new Event prepopulated with fields, dynamic
 // field values, and configuration inherited from the builder.
 func (b *Builder) NewEvent() *Event {
-	e := &Event{
-		WriteKey:   b.WriteKey,
-		Dataset:    b.Dataset,
-		SampleRate: b.SampleRate,
-		APIHost:    b.APIHost,
-		Timestamp:  time.Now(),
-		client:     b.client,
+	var e *Event
+	if b.client != nil && b.client.poolEvents {
+		e = eventPool.Get().(*Event)
+	} else {
+		e = &Event{}
 	}
-	e.data = make(map[string]interface{})
-
+	e.WriteKey = b.WriteKey
+	e.Dataset = b.Dataset
+	e.SampleRate = b.SampleRate
+	e.APIHost = b.APIHost
+	e.Timestamp = time.Now()
+	e.client = b.client
+
+	// the event shares the builder's fields until either of them changes
 	b.lock.RLock()
-	defer b.lock.RUnlock()
-	for k, v := range b.data {
-		e.data[k] = v
-	}
-	// create dynamic metrics
+	e.data = b.share()
+	e.shared = 1
+	e.schema = b.schema
+	b.lock.RUnlock()
+
+	// create dynamic metrics. RemoveDynamicField replaces the slice rather
+	// than modifying it, so the functions can run, and any schema violations
+	// be reported, without holding the builder's locks.
 	b.dynFieldsLock.RLock()
-	defer b.dynFieldsLock.RUnlock()
-	for _, dynField := range b.dynFields {
-		e.AddField(dynField.name, dynField.fn())
+	dynFields := b.dynFields
+	b.dynFieldsLock.RUnlock()
+	var violations schemaViolations
+	for _, dynField := range dynFields {
+		if dynField.sendFn != nil {
+			e.sendFields = append(e.sendFields, dynField)
+			continue
+		}
+		val := dynField.fn()
+		e.lock.Lock()
+		e.set(dynField.name, val, &violations)
+		e.lock.Unlock()
 	}
+	violations.report()
 	return e
 }
 
 // Clone creates a new builder that inherits all traits of this builder and
 // creates its own scope in which to add additional static and dynamic fields.
+// The clone starts with a copy of this builder's fields, dynamic fields and
+// schema; after that the two are independent. Fields added to or removed from
+// the clone (with RemoveField or RemoveDynamicField) don't affect this
+// builder, and fields added to this builder later aren't seen by the clone.
 func (b *Builder) Clone() *Builder {
 	newB := &Builder{
 		WriteKey:   b.WriteKey,
@@ -887,12 +1180,11 @@ func (b *Builder) Clone() *Builder {
 		dynFields:  make([]dynamicField, 0, len(b.dynFields)),
 		client:     b.client,
 	}
-	newB.data = make(map[string]interface{})
 	b.lock.RLock()
 	defer b.lock.RUnlock()
-	for k, v := range b.data {
-		newB.data[k] = v
-	}
+	newB.data = b.share()
+	newB.shared = 1
+	newB.schema = b.schema
 	// copy dynamic metric generators
 	b.dynFieldsLock.RLock()
 	defer b.dynFieldsLock.RUnlock()
@@ -2,6 +2,7 @@ package libhoney
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -189,6 +190,19 @@ func TestAddStruct(t *testing.T) {
 		string(marshalled))
 }
 
+func TestMarshalEscapesKeys(t *testing.T) {
+	resetPackageVars()
+	ev := NewEvent()
+	ev.AddField(`quote"key`, 1)
+	ev.AddField("back\\slash", 2)
+	ev.AddField("ctrl\n", 3)
+	marshalled, err := json.Marshal(ev.data)
+	assert.Nil(t, err)
+	var decoded map[string]int
+	assert.Nil(t, json.Unmarshal(marshalled, &decoded))
+	assert.Equal(t, map[string]int{`quote"key`: 1, "back\\slash": 2, "ctrl\n": 3}, decoded)
+}
+
 func TestAddStructPtr(t *testing.T) {
 	resetPackageVars()
 	intPtr := new(int)
@@ -446,6 +460,99 @@ func TestCloneBuilder(t *testing.T) {
 	testEquals(t, b2.APIHost, "differentAPIHost")
 }
 
+func TestCloneBuilderFields(t *testing.T) {
+	resetPackageVars()
+	parent := NewBuilder()
+	parent.AddField("service", "api")
+	parent.AddField("secret", "hunter2")
+	parent.AddDynamicField("dyn", func() interface{} { return 1 })
+
+	// the child starts with a copy of the parent's fields and can shed them
+	child := parent.Clone()
+	testEquals(t, child.Fields(), map[string]interface{}{"service": "api", "secret": "hunter2"})
+	child.RemoveField("secret")
+	testEquals(t, child.RemoveDynamicField("dyn"), true)
+	testEquals(t, child.HasField("secret"), false)
+	testEquals(t, parent.HasField("secret"), true)
+	testEquals(t, len(parent.dynFields), 1)
+
+	// fields added to the parent after cloning aren't inherited
+	parent.AddField("late", true)
+	testEquals(t, child.HasField("late"), false)
+	child.AddField("child", true)
+	testEquals(t, parent.HasField("child"), false)
+
+	testEquals(t, map[string]interface{}(child.NewEvent().data), map[string]interface{}{
+		"service": "api",
+		"child":   true,
+	})
+	testEquals(t, map[string]interface{}(parent.NewEvent().data), map[string]interface{}{
+		"service": "api",
+		"secret":  "hunter2",
+		"late":    true,
+		"dyn":     1,
+	})
+}
+
+func TestFieldInspection(t *testing.T) {
+	resetPackageVars()
+	b := NewBuilder()
+	b.AddField("a", 1)
+	b.AddField("nil", nil)
+
+	val, ok := b.GetField("a")
+	testEquals(t, val, 1)
+	testEquals(t, ok, true)
+	val, ok = b.GetField("nil")
+	testEquals(t, val, nil)
+	testEquals(t, ok, true)
+	_, ok = b.GetField("missing")
+	testEquals(t, ok, false)
+
+	// Fields returns a copy
+	fields := b.Fields()
+	fields["b"] = 2
+	delete(fields, "a")
+	testEquals(t, b.HasField("a"), true)
+	testEquals(t, b.HasField("b"), false)
+	b.RemoveField("missing")
+	b.RemoveField("nil")
+	testEquals(t, b.Fields(), map[string]interface{}{"a": 1})
+
+	ev := b.NewEvent()
+	ev.AddField("b", 2)
+	ev.RemoveField("a")
+	testEquals(t, ev.Fields(), map[string]interface{}{"b": 2})
+	testEquals(t, b.HasField("a"), true)
+	testOK(t, ev.Send())
+
+	// removes after sending have no effect
+	ev.RemoveField("b")
+	testEquals(t, ev.HasField("b"), true)
+}
+
+func TestFieldsConcurrentAccess(t *testing.T) {
+	resetPackageVars()
+	ev := NewEvent()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("k%d-%d", i, j)
+				ev.AddField(key, j)
+				for range ev.Fields() {
+				}
+				ev.GetField(key)
+				ev.RemoveField(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+	testEquals(t, len(ev.Fields()), 0)
+}
+
 func TestBuilderDynFields(t *testing.T) {
 	resetPackageVars()
 	var i int
@@ -497,6 +604,64 @@ func TestBuilderDynFields(t *testing.T) {
 	testEquals(t, ev5.data["floats"], 1.0)
 }
 
+type ctxTestKey struct{}
+
+func TestBuilderSendTimeDynFields(t *testing.T) {
+	resetPackageVars()
+	b := NewBuilder()
+	b.AddDynamicField("created", func() interface{} { return "at creation" })
+	testOK(t, b.AddDynamicFieldFunc("duration_ms", func(ctx context.Context, ev *Event) interface{} {
+		return float64(time.Since(ev.Timestamp)) / float64(time.Millisecond)
+	}))
+	testOK(t, b.AddDynamicFieldFunc("request_id", func(ctx context.Context, ev *Event) interface{} {
+		return ctx.Value(ctxTestKey{})
+	}))
+	testOK(t, b.AddDynamicFieldFunc("field_count", func(ctx context.Context, ev *Event) interface{} {
+		ev.lock.RLock()
+		defer ev.lock.RUnlock()
+		return len(ev.data)
+	}))
+
+	ev := b.NewEvent()
+	// send-time fields aren't evaluated at creation
+	testEquals(t, len(ev.data), 1)
+	ev.Timestamp = time.Now().Add(-time.Second)
+	ev.AddField("a", 1)
+	testOK(t, ev.SendCtx(context.WithValue(context.Background(), ctxTestKey{}, "abc")))
+
+	sent := dc.transmission.(*transmission.MockSender).Events()
+	testEquals(t, len(sent), 1)
+	testEquals(t, sent[0].Data["request_id"], "abc")
+	testEquals(t, sent[0].Data["created"], "at creation")
+	testEquals(t, sent[0].Data["field_count"], 4)
+	testEquals(t, sent[0].Data["duration_ms"].(float64) >= 1000, true)
+
+	// they aren't evaluated again once the event is sent
+	ev.addSendFields(context.Background())
+	testEquals(t, sent[0].Data["request_id"], "abc")
+
+	// the event's set of send-time fields is fixed when it is created
+	ev = b.NewEvent()
+	testEquals(t, b.RemoveDynamicField("request_id"), true)
+	testEquals(t, b.RemoveDynamicField("request_id"), false)
+	testEquals(t, b.RemoveDynamicField("created"), true)
+	testOK(t, ev.SendPresampled())
+	ev2 := b.NewEvent()
+	ev2.AddField("a", 1)
+	testOK(t, ev2.Send())
+
+	sent = dc.transmission.(*transmission.MockSender).Events()
+	testEquals(t, len(sent), 3)
+	_, ok := sent[1].Data["request_id"]
+	testEquals(t, ok, true)
+	testEquals(t, sent[1].Data["request_id"], nil)
+	_, ok = sent[2].Data["request_id"]
+	testEquals(t, ok, false)
+	_, ok = sent[2].Data["created"]
+	testEquals(t, ok, false)
+	testEquals(t, len(b.dynFields), 2)
+}
+
 func TestBuilderStaticFields(t *testing.T) {
 	resetPackageVars()
 	// test you can add fields to a builder and events get them
@@ -952,6 +1117,162 @@ func ExampleAddDynamicField() {
 		func() interface{} { return runtime.NumGoroutine() })
 }
 
+func TestCopyOnWriteFields(t *testing.T) {
+	resetPackageVars()
+	b := NewBuilder()
+	b.AddField("a", 1)
+
+	// events share the builder's fields until either side changes them
+	ev1 := b.NewEvent()
+	ev2 := b.NewEvent()
+	ev1.AddField("b", 2)
+	ev2.RemoveField("a")
+	b.AddField("c", 3)
+
+	testEquals(t, b.Fields(), map[string]interface{}{"a": 1, "c": 3})
+	testEquals(t, ev1.Fields(), map[string]interface{}{"a": 1, "b": 2})
+	testEquals(t, ev2.Fields(), map[string]interface{}{})
+	testEquals(t, b.NewEvent().Fields(), map[string]interface{}{"a": 1, "c": 3})
+
+	// sending an event that never changed its fields sends the builder's
+	ev3 := b.NewEvent()
+	testOK(t, ev3.Send())
+	b.AddField("d", 4)
+	sent := dc.transmission.(*transmission.MockSender).Events()
+	testEquals(t, sent[0].Data, map[string]interface{}{"a": 1, "c": 3})
+}
+
+func TestEventPooling(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c, err := NewClient(ClientConfig{
+		Dataset:      "pool",
+		Transmission: &transmission.WriterSender{W: buf},
+		PoolEvents:   true,
+	})
+	testOK(t, err)
+	b := c.NewBuilder()
+	b.AddField("service", "api")
+
+	for i := 0; i < 3; i++ {
+		ev := b.NewEvent()
+		testEquals(t, ev.pooled, true)
+		ev.AddField("i", i)
+		ev.Metadata = i
+		testOK(t, ev.Send())
+
+		// the writer sender releases events as soon as they're written
+		testEquals(t, ev.data, marshallableMap(nil))
+		testEquals(t, ev.Metadata, nil)
+		testEquals(t, ev.sent, false)
+		testEquals(t, len(ev.spare), 0)
+		testNotEquals(t, ev.spare, marshallableMap(nil))
+		testEquals(t, ev.releaseTx != nil, true)
+		testEquals(t, (<-c.TxResponses()).Metadata, i)
+	}
+	testEquals(t, b.Fields(), map[string]interface{}{"service": "api"})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	testEquals(t, len(lines), 3)
+	testEquals(t, lines[2], `{"data":{"i":2,"service":"api"},"time":`+lines[2][strings.Index(lines[2], `"time":`)+7:])
+
+	// events dropped by sampling are released too
+	b.SampleRate = 1 << 30
+	ev := b.NewEvent()
+	ev.AddField("i", 3)
+	testOK(t, ev.Send())
+	testEquals(t, ev.data, marshallableMap(nil))
+
+	// events that fail to send stay with the caller
+	b.Dataset = ""
+	ev = b.NewEvent()
+	ev.AddField("i", 4)
+	testErr(t, ev.SendPresampled())
+	testEquals(t, ev.data["i"], 4)
+}
+
+// Pooled events are recycled from the transmission's goroutines while new ones
+// are being built; run with -race.
+func TestEventPoolingConcurrent(t *testing.T) {
+	server := startFakeServer(t, DefaultMaxBatchSize)
+	defer server.Close()
+	c, err := NewClient(ClientConfig{
+		APIKey:     "pool",
+		Dataset:    "pool",
+		APIHost:    server.URL,
+		PoolEvents: true,
+	})
+	testOK(t, err)
+	b := c.NewBuilder()
+	b.AddField("service", "api")
+
+	const workers, perWorker = 4, 200
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ev := b.NewEvent()
+				ev.AddField("worker", w)
+				ev.Metadata = w*perWorker + i
+				testOK(t, ev.Send())
+			}
+		}(w)
+	}
+	seen := make(map[interface{}]bool)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range c.TxResponses() {
+			testOK(t, r.Err)
+			seen[r.Metadata] = true
+		}
+	}()
+	wg.Wait()
+	c.Close()
+	<-done
+	testEquals(t, len(seen), workers*perWorker)
+}
+
+func benchmarkSend(b *testing.B, pool bool, eventFields int) {
+	c, err := NewClient(ClientConfig{
+		APIKey:       "bench",
+		Dataset:      "bench",
+		Transmission: &transmission.DiscardSender{},
+		PoolEvents:   pool,
+	})
+	testOK(b, err)
+	builder := c.NewBuilder()
+	for i := 0; i < 20; i++ {
+		builder.AddField(fmt.Sprintf("builder_field_%d", i), i)
+	}
+	keys := make([]string, eventFields)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("event_field_%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		ev := builder.NewEvent()
+		for i, k := range keys {
+			ev.AddField(k, i)
+		}
+		ev.SendPresampled()
+	}
+}
+
+// Compare allocations with and without pooling, for events that only inherit
+// their builder's fields and for events that add their own.
+func BenchmarkSendBuilderFieldsOnly(b *testing.B) {
+	b.Run("unpooled", func(b *testing.B) { benchmarkSend(b, false, 0) })
+	b.Run("pooled", func(b *testing.B) { benchmarkSend(b, true, 0) })
+}
+
+func BenchmarkSendWithEventFields(b *testing.B) {
+	b.Run("unpooled", func(b *testing.B) { benchmarkSend(b, false, 5) })
+	b.Run("pooled", func(b *testing.B) { benchmarkSend(b, true, 5) })
+}
+
 func BenchmarkInit(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		Init(Config{
@@ -0,0 +1,252 @@
+package libhoney
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Column names used by the log adapters for the well-known parts of a log
+// record. Any other fields on the record are added to the event as-is.
+const (
+	LogLevelField   = "log.level"
+	LogMessageField = "log.message"
+	LogCallerField  = "log.caller"
+	// LogCountField counts the records attached to an event from context.
+	LogCountField = "log.count"
+)
+
+// LogRecord is a single log entry as handed to a LogHook. Level is compared
+// case-insensitively; Fields are added to the event as individual columns.
+type LogRecord struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Caller  string
+	Fields  map[string]interface{}
+
+	// Context, if set and carrying an event (see ContextWithEvent), causes the
+	// record to be added to that event rather than sent as its own event.
+	Context context.Context
+}
+
+// LogHook forwards log records to Honeycomb. Its Fire method is intended to be
+// called from a hook or sink of a structured logging library, translating that
+// library's entry type into a LogRecord.
+//
+// When the record's context carries an event, the record is attached to that
+// event. An event can only hold one level, message and caller, so of the
+// records attached to it only the most severe (the latest, among records of
+// equal severity) sets them and adds its fields; LogCountField counts them
+// all. Otherwise a new event is created from Builder (or the
+// package-level builder if Builder is nil) and sent, sampled according to
+// SampleRates.
+type LogHook struct {
+	// Builder is used to create events for records that aren't attached to an
+	// event from context. If nil, the package-level client is used.
+	Builder *Builder
+
+	// SampleRates maps a lower-case level name to the sample rate for events
+	// created at that level, e.g. {"debug": 100}. Levels that aren't present
+	// use the builder's sample rate.
+	SampleRates map[string]uint
+
+	// Levels restricts which levels are forwarded. If empty, all levels are.
+	Levels []string
+}
+
+// Fire sends or attaches the record.
+func (h *LogHook) Fire(r *LogRecord) error {
+	level := strings.ToLower(r.Level)
+	if !h.enabled(level) {
+		return nil
+	}
+
+	if ev := EventFromContext(r.Context); ev != nil {
+		return h.attachRecord(ev, level, r)
+	}
+
+	var ev *Event
+	if h.Builder != nil {
+		ev = h.Builder.NewEvent()
+	} else {
+		ev = NewEvent()
+	}
+	if rate, ok := h.SampleRates[level]; ok {
+		ev.SampleRate = rate
+	}
+	if !r.Time.IsZero() {
+		ev.Timestamp = r.Time
+	}
+	if err := h.addRecord(&ev.fieldHolder, level, r); err != nil {
+		return err
+	}
+	return ev.SendCtx(r.Context)
+}
+
+func (h *LogHook) enabled(level string) bool {
+	if len(h.Levels) == 0 {
+		return true
+	}
+	for _, l := range h.Levels {
+		if strings.EqualFold(l, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachRecord adds r to ev, which may already carry earlier records. The
+// event's send lock is held throughout so that concurrent records are
+// counted correctly and none is added once ev has been sent.
+func (h *LogHook) attachRecord(ev *Event, level string, r *LogRecord) error {
+	ev.sendLock.Lock()
+	defer ev.sendLock.Unlock()
+	if ev.sent {
+		return nil
+	}
+	ev.lock.RLock()
+	count, _ := ev.data[LogCountField].(int)
+	prevLevel, _ := ev.data[LogLevelField].(string)
+	ev.lock.RUnlock()
+
+	ev.fieldHolder.AddField(LogCountField, count+1)
+	if count > 0 && levelSeverity(level) < levelSeverity(prevLevel) {
+		return nil
+	}
+	return h.addRecord(&ev.fieldHolder, level, r)
+}
+
+func (h *LogHook) addRecord(f *fieldHolder, level string, r *LogRecord) error {
+	if len(r.Fields) > 0 {
+		if err := f.Add(r.Fields); err != nil {
+			return err
+		}
+	}
+	if level != "" {
+		f.AddField(LogLevelField, level)
+	}
+	if r.Message != "" {
+		f.AddField(LogMessageField, r.Message)
+	}
+	if r.Caller != "" {
+		f.AddField(LogCallerField, r.Caller)
+	}
+	return nil
+}
+
+// levelSeverity orders the lower-case level names used by common logging
+// libraries. Unknown levels rank with info.
+func levelSeverity(level string) int {
+	switch level {
+	case "trace":
+		return 0
+	case "debug":
+		return 1
+	case "warn", "warning":
+		return 3
+	case "error":
+		return 4
+	case "fatal", "panic", "dpanic", "critical":
+		return 5
+	}
+	return 2
+}
+
+// LogWriter is an io.Writer suitable for use as the output of a log.Logger
+// from the standard library. Each line written is parsed as a JSON object;
+// the "level", "msg"/"message", "caller" and "time"/"ts" keys fill in the
+// corresponding parts of the record and every other key becomes a field.
+// Lines that aren't JSON objects are forwarded as a message at DefaultLevel.
+type LogWriter struct {
+	// Hook receives the parsed records.
+	Hook *LogHook
+
+	// DefaultLevel is the level used for lines that don't specify one.
+	DefaultLevel string
+
+	ctx context.Context
+
+	// buf holds a partial line between calls to Write
+	buf  []byte
+	lock sync.Mutex
+}
+
+// WithContext returns a new LogWriter sharing w's configuration whose records
+// will attach to the event carried by ctx, if there is one.
+func (w *LogWriter) WithContext(ctx context.Context) *LogWriter {
+	return &LogWriter{
+		Hook:         w.Hook,
+		DefaultLevel: w.DefaultLevel,
+		ctx:          ctx,
+	}
+}
+
+// Write implements io.Writer. It always consumes all of p; errors from
+// sending individual records are returned after the remaining lines have been
+// processed.
+func (w *LogWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.buf = append(w.buf, p...)
+	var firstErr error
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		line := bytes.TrimSpace(w.buf[:idx])
+		w.buf = w.buf[idx+1:]
+		if len(line) == 0 {
+			continue
+		}
+		if err := w.Hook.Fire(w.parseLine(line)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(p), firstErr
+}
+
+func (w *LogWriter) parseLine(line []byte) *LogRecord {
+	r := &LogRecord{
+		Level:   w.DefaultLevel,
+		Context: w.ctx,
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		r.Message = string(line)
+		return r
+	}
+	for k, v := range fields {
+		switch k {
+		case "level", "lvl":
+			if s, ok := v.(string); ok {
+				r.Level = s
+				delete(fields, k)
+			}
+		case "msg", "message":
+			if s, ok := v.(string); ok {
+				r.Message = s
+				delete(fields, k)
+			}
+		case "caller":
+			if s, ok := v.(string); ok {
+				r.Caller = s
+				delete(fields, k)
+			}
+		case "time", "ts":
+			if s, ok := v.(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+					r.Time = t
+					delete(fields, k)
+				}
+			}
+		}
+	}
+	r.Fields = fields
+	return r
+}
@@ -0,0 +1,111 @@
+package libhoney
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+func TestLogWriter(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "foo",
+		Transmission: mock,
+	})
+	testOK(t, err)
+
+	w := &LogWriter{
+		Hook:         &LogHook{Builder: c.NewBuilder()},
+		DefaultLevel: "info",
+	}
+	logger := log.New(w, "", 0)
+	logger.Print(`{"level":"ERROR","msg":"it broke","caller":"main.go:12","user_id":5}`)
+	logger.Print("not json")
+
+	evs := mock.Events()
+	testEquals(t, len(evs), 2)
+	testEquals(t, evs[0].Data[LogLevelField], "error")
+	testEquals(t, evs[0].Data[LogMessageField], "it broke")
+	testEquals(t, evs[0].Data[LogCallerField], "main.go:12")
+	testEquals(t, evs[0].Data["user_id"], float64(5))
+	testEquals(t, evs[1].Data[LogLevelField], "info")
+	testEquals(t, evs[1].Data[LogMessageField], "not json")
+}
+
+func TestLogHookAttachesToContextEvent(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "foo",
+		Transmission: mock,
+	})
+	testOK(t, err)
+
+	hook := &LogHook{Builder: c.NewBuilder()}
+	ev := c.NewEvent()
+	ctx := ContextWithEvent(context.Background(), ev)
+	err = hook.Fire(&LogRecord{
+		Level:   "warn",
+		Message: "slow query",
+		Fields:  map[string]interface{}{"db.rows": 12},
+		Context: ctx,
+	})
+	testOK(t, err)
+
+	// nothing is sent until the owner of the event sends it
+	testEquals(t, len(mock.Events()), 0)
+	testEquals(t, ev.data[LogLevelField], "warn")
+	testEquals(t, ev.data["db.rows"], 12)
+}
+
+func TestLogHookKeepsMostSevereContextRecord(t *testing.T) {
+	c, err := NewClient(ClientConfig{APIKey: "foo", Transmission: &transmission.MockSender{}})
+	testOK(t, err)
+	hook := &LogHook{}
+	ev := c.NewEvent()
+	ctx := ContextWithEvent(context.Background(), ev)
+
+	for _, r := range []*LogRecord{
+		{Level: "info", Message: "started", Fields: map[string]interface{}{"step": 1}},
+		{Level: "error", Message: "it broke", Fields: map[string]interface{}{"step": 2}},
+		{Level: "warn", Message: "retrying", Fields: map[string]interface{}{"step": 3}},
+	} {
+		r.Context = ctx
+		testOK(t, hook.Fire(r))
+	}
+	testEquals(t, ev.data[LogCountField], 3)
+	testEquals(t, ev.data[LogLevelField], "error")
+	testEquals(t, ev.data[LogMessageField], "it broke")
+	testEquals(t, ev.data["step"], 2)
+
+	// a later record of equal severity wins
+	testOK(t, hook.Fire(&LogRecord{Level: "ERROR", Message: "gave up", Context: ctx}))
+	testEquals(t, ev.data[LogCountField], 4)
+	testEquals(t, ev.data[LogMessageField], "gave up")
+
+	// nothing is attached once the event is sent
+	testOK(t, ev.Send())
+	testOK(t, hook.Fire(&LogRecord{Level: "fatal", Message: "too late", Context: ctx}))
+	testEquals(t, ev.data[LogMessageField], "gave up")
+}
+
+func TestLogHookLevels(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "foo",
+		Transmission: mock,
+	})
+	testOK(t, err)
+
+	hook := &LogHook{
+		Builder:     c.NewBuilder(),
+		Levels:      []string{"info", "error"},
+		SampleRates: map[string]uint{"info": 1},
+	}
+	testOK(t, hook.Fire(&LogRecord{Level: "debug", Message: "skipped"}))
+	testOK(t, hook.Fire(&LogRecord{Level: "INFO", Message: "kept"}))
+	evs := mock.Events()
+	testEquals(t, len(evs), 1)
+	testEquals(t, evs[0].Data[LogMessageField], "kept")
+}
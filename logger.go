@@ -3,6 +3,8 @@ package libhoney
 import (
 	"fmt"
 	"log"
+
+	"github.com/honeycombio/libhoney-go/internal/logging"
 )
 
 // Logger is used to log extra info within the SDK detailing what's happening.
@@ -16,16 +18,88 @@ type Logger interface {
 	Printf(msg string, args ...interface{})
 }
 
-// DefaultLogger implements Logger and prints messages to stdout prepended by a
-// timestamp (RFC3339 formatted)
-type DefaultLogger struct{}
+// LeveledLogger is an optional extension of Logger. If the Logger you supply
+// also has the methods
+//
+//	Debug(msg string, keyvals ...interface{})
+//	Info(msg string, keyvals ...interface{})
+//	Warn(msg string, keyvals ...interface{})
+//	Error(msg string, keyvals ...interface{})
+//
+// the SDK will call the one matching the severity of each message instead of
+// Printf, passing context as alternating key/value pairs. This lets you keep
+// errors while discarding the per-event debug output. transmission.LeveledLogger
+// is the same interface, so one implementation serves both packages.
+type LeveledLogger interface {
+	logging.LeveledLogger
+}
+
+// LogLevel is the severity of a message logged by the SDK.
+type LogLevel int
 
-// Printf prints the message to stdout.
+// The levels at which the SDK logs, in increasing order of severity.
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// DefaultLogger implements Logger and LeveledLogger and prints messages to
+// stdout prepended by a timestamp (RFC3339 formatted)
+type DefaultLogger struct {
+	// Level is the minimum severity that will be printed. The zero value
+	// prints everything, including per-event debug messages.
+	Level LogLevel
+}
+
+// Printf prints the message to stdout at DEBUG level.
 func (d *DefaultLogger) Printf(msg string, args ...interface{}) {
+	d.printf(LogLevelDebug, msg, args...)
+}
+
+// Debug prints the message and key/value pairs at DEBUG level.
+func (d *DefaultLogger) Debug(msg string, keyvals ...interface{}) {
+	d.printf(LogLevelDebug, "%s", logging.Message{Msg: msg, Keyvals: keyvals})
+}
+
+// Info prints the message and key/value pairs at INFO level.
+func (d *DefaultLogger) Info(msg string, keyvals ...interface{}) {
+	d.printf(LogLevelInfo, "%s", logging.Message{Msg: msg, Keyvals: keyvals})
+}
+
+// Warn prints the message and key/value pairs at WARN level.
+func (d *DefaultLogger) Warn(msg string, keyvals ...interface{}) {
+	d.printf(LogLevelWarn, "%s", logging.Message{Msg: msg, Keyvals: keyvals})
+}
+
+// Error prints the message and key/value pairs at ERROR level.
+func (d *DefaultLogger) Error(msg string, keyvals ...interface{}) {
+	d.printf(LogLevelError, "%s", logging.Message{Msg: msg, Keyvals: keyvals})
+}
+
+func (d *DefaultLogger) printf(level LogLevel, msg string, args ...interface{}) {
+	if level < d.Level {
+		return
+	}
 	// use the same format as the python libhoney:
 	// '%(asctime)s - %(name)s - %(levelname)s - %(message)s')
 	// except for go's more friendly rfc3339nano rather than asctime
-	msg = fmt.Sprintf("%s - %s - %s", "libhoney", "DEBUG", msg)
+	msg = fmt.Sprintf("%s - %s - %s", "libhoney", level, msg)
 	log.Printf(msg+"\n", args...)
 }
 
@@ -35,3 +109,10 @@ type nullLogger struct{}
 func (n *nullLogger) Printf(msg string, args ...interface{}) {
 	// nothing to see here.
 }
+
+// isNullLogger reports whether l discards everything, so that hot paths can
+// skip building log arguments.
+func isNullLogger(l Logger) bool {
+	_, ok := l.(*nullLogger)
+	return ok
+}
@@ -0,0 +1,103 @@
+package libhoney
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/internal/logging"
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (r *recordingLogger) Printf(msg string, args ...interface{}) {
+	r.lines = append(r.lines, "PRINTF "+fmt.Sprintf(msg, args...))
+}
+func (r *recordingLogger) Debug(msg string, keyvals ...interface{}) {
+	r.lines = append(r.lines, "DEBUG "+logging.Format(msg, keyvals))
+}
+func (r *recordingLogger) Info(msg string, keyvals ...interface{}) {
+	r.lines = append(r.lines, "INFO "+logging.Format(msg, keyvals))
+}
+func (r *recordingLogger) Warn(msg string, keyvals ...interface{}) {
+	r.lines = append(r.lines, "WARN "+logging.Format(msg, keyvals))
+}
+func (r *recordingLogger) Error(msg string, keyvals ...interface{}) {
+	r.lines = append(r.lines, "ERROR "+logging.Format(msg, keyvals))
+}
+
+// a LeveledLogger must be usable as the transmission's logger too
+var _ transmission.LeveledLogger = &recordingLogger{}
+
+func TestLeveledLogger(t *testing.T) {
+	l := &recordingLogger{}
+	c, err := NewClient(ClientConfig{
+		Transmission: &transmission.MockSender{},
+		Logger:       l,
+	})
+	testOK(t, err)
+
+	ev := c.NewEvent()
+	testErr(t, ev.Send())
+	c.Close()
+
+	testEquals(t, len(l.lines), 2)
+	testEquals(t, strings.HasPrefix(l.lines[0], "ERROR failed to send event err=No metrics added"), true, l.lines[0])
+	testEquals(t, l.lines[1], "INFO closing libhoney client")
+}
+
+func TestPrintfLoggerFallback(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c, err := NewClient(ClientConfig{
+		Transmission: &transmission.MockSender{},
+		Logger:       log.New(buf, "", 0),
+	})
+	testOK(t, err)
+	c.Flush()
+	c.Close()
+	testEquals(t, buf.String(), "flushing libhoney client\nclosing libhoney client\n")
+}
+
+func TestDefaultLoggerLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+	log.SetFlags(0)
+	defer log.SetFlags(log.LstdFlags)
+
+	l := &DefaultLogger{Level: LogLevelWarn}
+	l.Printf("hidden %d", 1)
+	l.Debug("hidden")
+	l.Info("hidden")
+	l.Warn("shown", "k", "v")
+	l.Error("also shown", "odd")
+	testEquals(t, buf.String(),
+		"libhoney - WARN - shown k=v\nlibhoney - ERROR - also shown odd=(MISSING)\n")
+}
+
+type countingStringer struct {
+	calls int
+}
+
+func (c *countingStringer) String() string {
+	c.calls++
+	return "counted"
+}
+
+func TestLoggingIsLazy(t *testing.T) {
+	val := &countingStringer{}
+	logging.Debug(&nullLogger{}, "discarded", "val", val)
+	logging.Debug(&DefaultLogger{Level: LogLevelInfo}, "filtered", "val", val)
+	testEquals(t, val.calls, 0)
+
+	buf := &bytes.Buffer{}
+	logging.Debug(log.New(buf, "", 0), "printed", "val", val)
+	testEquals(t, buf.String(), "printed val=counted\n")
+	testEquals(t, val.calls, 1)
+}
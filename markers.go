@@ -0,0 +1,154 @@
+package libhoney
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Marker annotates a point or range in time on a dataset's graphs in
+// Honeycomb, such as a deploy.
+type Marker struct {
+	// ID is assigned by Honeycomb when the marker is created.
+	ID string
+
+	// Message is the text shown on the marker, e.g. "deploy #123".
+	Message string
+	// Type groups markers; markers of the same type share a color.
+	Type string
+	// URL links the marker to more detail, such as a build or PR.
+	URL string
+
+	// StartTime is when the marker begins. If zero, Honeycomb uses the time
+	// the marker is created.
+	StartTime time.Time
+	// EndTime, if set, turns the marker into a range.
+	EndTime time.Time
+
+	// CreatedAt, UpdatedAt and Color are set by Honeycomb and ignored when
+	// creating or updating a marker.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Color     string
+}
+
+// markerJSON is the wire format of a Marker: times are unix seconds on the way
+// in and RFC3339 strings on the way out.
+type markerJSON struct {
+	ID        string     `json:"id,omitempty"`
+	Message   string     `json:"message,omitempty"`
+	Type      string     `json:"type,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	StartTime int64      `json:"start_time,omitempty"`
+	EndTime   int64      `json:"end_time,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Color     string     `json:"color,omitempty"`
+}
+
+func (m Marker) MarshalJSON() ([]byte, error) {
+	aux := markerJSON{
+		Message: m.Message,
+		Type:    m.Type,
+		URL:     m.URL,
+	}
+	if !m.StartTime.IsZero() {
+		aux.StartTime = m.StartTime.Unix()
+	}
+	if !m.EndTime.IsZero() {
+		aux.EndTime = m.EndTime.Unix()
+	}
+	return json.Marshal(aux)
+}
+
+func (m *Marker) UnmarshalJSON(b []byte) error {
+	var aux markerJSON
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	*m = Marker{
+		ID:      aux.ID,
+		Message: aux.Message,
+		Type:    aux.Type,
+		URL:     aux.URL,
+		Color:   aux.Color,
+	}
+	if aux.StartTime != 0 {
+		m.StartTime = time.Unix(aux.StartTime, 0)
+	}
+	if aux.EndTime != 0 {
+		m.EndTime = time.Unix(aux.EndTime, 0)
+	}
+	if aux.CreatedAt != nil {
+		m.CreatedAt = *aux.CreatedAt
+	}
+	if aux.UpdatedAt != nil {
+		m.UpdatedAt = *aux.UpdatedAt
+	}
+	return nil
+}
+
+// MarkersClient creates, updates, lists and deletes the markers on one dataset
+// through the Honeycomb markers API (/1/markers/<dataset>). Get one from
+// Client.Markers. Errors from the API are returned as *APIError.
+type MarkersClient struct {
+	api     *apiClient
+	dataset string
+}
+
+// Markers returns a MarkersClient for dataset, or for the client's default
+// dataset if dataset is empty. Use "__all__" for markers that apply to every
+// dataset in an environment. Requests use the client's API host, API key and,
+// if its transmission is a transmission.Honeycomb, that transmission's
+// RoundTripper.
+func (c *Client) Markers(dataset string) *MarkersClient {
+	c.ensureTransmission()
+	c.ensureBuilder()
+	if dataset == "" {
+		dataset = c.builder.Dataset
+	}
+	return &MarkersClient{
+		api:     newAPIClient(c.builder.APIHost, c.builder.WriteKey, nil, c.transmission),
+		dataset: dataset,
+	}
+}
+
+// Markers returns a MarkersClient for dataset using the package-level client's
+// settings. See Client.Markers.
+func Markers(dataset string) *MarkersClient {
+	return dc.Markers(dataset)
+}
+
+// Create adds a marker to the dataset and returns it as stored by Honeycomb,
+// including its ID.
+func (mc *MarkersClient) Create(ctx context.Context, m Marker) (Marker, error) {
+	var created Marker
+	err := mc.api.do(ctx, "POST", m, &created, "1", "markers", mc.dataset)
+	return created, err
+}
+
+// Update replaces the message, type, URL and times of the marker with m.ID.
+func (mc *MarkersClient) Update(ctx context.Context, m Marker) (Marker, error) {
+	if m.ID == "" {
+		return Marker{}, errors.New("can't update a marker without an ID")
+	}
+	var updated Marker
+	err := mc.api.do(ctx, "PUT", m, &updated, "1", "markers", mc.dataset, m.ID)
+	return updated, err
+}
+
+// List returns all the markers on the dataset.
+func (mc *MarkersClient) List(ctx context.Context) ([]Marker, error) {
+	var markers []Marker
+	err := mc.api.do(ctx, "GET", nil, &markers, "1", "markers", mc.dataset)
+	return markers, err
+}
+
+// Delete removes the marker with the given ID.
+func (mc *MarkersClient) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return errors.New("can't delete a marker without an ID")
+	}
+	return mc.api.do(ctx, "DELETE", nil, nil, "1", "markers", mc.dataset, id)
+}
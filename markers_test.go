@@ -0,0 +1,122 @@
+package libhoney
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// fakeMarkersAPI is a minimal in-memory stand-in for /1/markers/<dataset>
+func fakeMarkersAPI(t *testing.T) *httptest.Server {
+	markers := map[string]map[string]interface{}{}
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Honeycomb-Team") != "markerkey" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		testEquals(t, r.Header.Get("User-Agent"), userAgent())
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/1/markers/"), "/")
+		testEquals(t, parts[0], "my dataset")
+		switch {
+		case r.Method == "POST" && len(parts) == 1:
+			m := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&m)
+			id := "m" + string(rune('0'+len(order)))
+			m["id"] = id
+			m["created_at"] = "2020-06-01T10:00:00Z"
+			markers[id] = m
+			order = append(order, id)
+			json.NewEncoder(w).Encode(m)
+		case r.Method == "GET" && len(parts) == 1:
+			list := []map[string]interface{}{}
+			for _, id := range order {
+				if m, ok := markers[id]; ok {
+					list = append(list, m)
+				}
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == "PUT" && len(parts) == 2:
+			m := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&m)
+			m["id"] = parts[1]
+			markers[parts[1]] = m
+			json.NewEncoder(w).Encode(m)
+		case r.Method == "DELETE" && len(parts) == 2:
+			if _, ok := markers[parts[1]]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"marker not found"}`))
+				return
+			}
+			delete(markers, parts[1])
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	return server
+}
+
+func TestMarkers(t *testing.T) {
+	server := fakeMarkersAPI(t)
+	defer server.Close()
+	c, err := NewClient(ClientConfig{
+		APIKey:       "markerkey",
+		APIHost:      server.URL,
+		Dataset:      "my dataset",
+		Transmission: &transmission.MockSender{},
+	})
+	testOK(t, err)
+	ctx := context.Background()
+	mc := c.Markers("")
+
+	start := time.Unix(1591005600, 0)
+	created, err := mc.Create(ctx, Marker{
+		Message:   "deploy #1",
+		Type:      "deploy",
+		URL:       "https://ci.example.com/1",
+		StartTime: start,
+	})
+	testOK(t, err)
+	testEquals(t, created.ID, "m0")
+	testEquals(t, created.Message, "deploy #1")
+	testEquals(t, created.StartTime.Equal(start), true)
+	testEquals(t, created.CreatedAt.Equal(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)), true)
+
+	created.EndTime = start.Add(time.Minute)
+	updated, err := mc.Update(ctx, created)
+	testOK(t, err)
+	testEquals(t, updated.EndTime.Equal(start.Add(time.Minute)), true)
+
+	list, err := mc.List(ctx)
+	testOK(t, err)
+	testEquals(t, len(list), 1)
+	testEquals(t, list[0].Type, "deploy")
+
+	testOK(t, mc.Delete(ctx, "m0"))
+	err = mc.Delete(ctx, "m0")
+	apiErr, ok := err.(*APIError)
+	testEquals(t, ok, true)
+	testEquals(t, apiErr.StatusCode, http.StatusNotFound)
+	testEquals(t, apiErr.Message, "marker not found")
+
+	_, err = mc.Update(ctx, Marker{Message: "no id"})
+	testErr(t, err)
+
+	bad, err := NewClient(ClientConfig{
+		APIKey:       "wrongkey",
+		APIHost:      server.URL,
+		Transmission: &transmission.MockSender{},
+	})
+	testOK(t, err)
+	_, err = bad.Markers("my dataset").List(ctx)
+	apiErr, ok = err.(*APIError)
+	testEquals(t, ok, true)
+	testEquals(t, apiErr.Is(ErrInvalidAPIKey), true)
+}
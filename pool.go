@@ -0,0 +1,51 @@
+package libhoney
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// maxPooledFields bounds the size of field maps kept for reuse, so that one
+// unusually large event doesn't pin its memory in the pool.
+const maxPooledFields = 128
+
+// eventPool recycles events for clients with PoolEvents set. Each pooled event
+// carries its own release function, allocated once, which the transmission
+// calls (via transmission.Event.Release) when it has finished with the event.
+var eventPool sync.Pool
+
+func init() {
+	eventPool.New = func() interface{} {
+		e := &Event{pooled: true}
+		e.releaseTx = func(*transmission.Event) {
+			releaseEvent(e)
+		}
+		return e
+	}
+}
+
+// releaseEvent resets a pooled event and returns it to the pool, keeping its
+// field map (if it owns one) for reuse.
+func releaseEvent(e *Event) {
+	spare := e.spare
+	if atomic.LoadInt32(&e.shared) == 0 && e.data != nil && len(e.data) <= maxPooledFields {
+		spare = e.data
+		for k := range spare {
+			delete(spare, k)
+		}
+	}
+	sendFields := e.sendFields
+	for i := range sendFields {
+		sendFields[i] = dynamicField{}
+	}
+	releaseTx := e.releaseTx
+
+	*e = Event{}
+	e.pooled = true
+	e.releaseTx = releaseTx
+	e.spare = spare
+	e.sendFields = sendFields[:0]
+	eventPool.Put(e)
+}
@@ -0,0 +1,119 @@
+package libhoney
+
+import "context"
+
+// PrefixedBuilder is a view of a Builder that prepends a prefix to the name of
+// every field it adds, so that libraries sharing a builder don't collide on
+// common names like "error" or "duration_ms". Fields are written straight to
+// the underlying builder; nothing is copied. Get one from Builder.WithPrefix.
+type PrefixedBuilder struct {
+	builder *Builder
+	prefix  string
+}
+
+// WithPrefix returns a view of the builder whose AddField, Add, AddFunc,
+// AddDynamicField and AddDynamicFieldFunc prepend prefix to field names. The
+// prefix is used as-is, so include any separator:
+// b.WithPrefix("db.").AddField("rows", 3) adds a field named "db.rows".
+func (b *Builder) WithPrefix(prefix string) *PrefixedBuilder {
+	return &PrefixedBuilder{builder: b, prefix: prefix}
+}
+
+// WithPrefix returns a view that adds prefix after this view's own prefix.
+func (p *PrefixedBuilder) WithPrefix(prefix string) *PrefixedBuilder {
+	return &PrefixedBuilder{builder: p.builder, prefix: p.prefix + prefix}
+}
+
+// Builder returns the builder this view writes to.
+func (p *PrefixedBuilder) Builder() *Builder {
+	return p.builder
+}
+
+// Prefix returns the prefix this view prepends to field names.
+func (p *PrefixedBuilder) Prefix() string {
+	return p.prefix
+}
+
+// AddField adds a field to the underlying builder, prefixing its name. See
+// Builder.AddField.
+func (p *PrefixedBuilder) AddField(key string, val interface{}) {
+	p.builder.AddField(p.prefix+key, val)
+}
+
+// Add adds each field of a struct or map to the underlying builder, prefixing
+// their names. See Builder.Add.
+func (p *PrefixedBuilder) Add(data interface{}) error {
+	return p.builder.addPrefixed(p.prefix, data)
+}
+
+// AddFunc adds the fields returned by fn to the underlying builder, prefixing
+// their names. See Builder.AddFunc.
+func (p *PrefixedBuilder) AddFunc(fn func() (string, interface{}, error)) error {
+	return p.builder.AddFunc(prefixFunc(p.prefix, fn))
+}
+
+// AddDynamicField adds a dynamic field to the underlying builder, prefixing
+// its name. See Builder.AddDynamicField.
+func (p *PrefixedBuilder) AddDynamicField(name string, fn func() interface{}) error {
+	return p.builder.AddDynamicField(p.prefix+name, fn)
+}
+
+// AddDynamicFieldFunc adds a send-time dynamic field to the underlying
+// builder, prefixing its name. See Builder.AddDynamicFieldFunc.
+func (p *PrefixedBuilder) AddDynamicFieldFunc(name string, fn func(context.Context, *Event) interface{}) error {
+	return p.builder.AddDynamicFieldFunc(p.prefix+name, fn)
+}
+
+// EventNamespace is a view of an Event that prepends a namespace to the name
+// of every field it adds. Fields are written straight to the underlying event;
+// nothing is copied. Get one from Event.Namespace.
+type EventNamespace struct {
+	event  *Event
+	prefix string
+}
+
+// Namespace returns a view of the event whose AddField, Add and AddFunc add
+// fields named "<name>.<field>": ev.Namespace("http").AddField("status", 200)
+// adds a field named "http.status". As with the event itself, adds through
+// the view after the event has been sent have no effect.
+func (e *Event) Namespace(name string) *EventNamespace {
+	return &EventNamespace{event: e, prefix: name + "."}
+}
+
+// Namespace returns a view nested inside this one, so that
+// ev.Namespace("http").Namespace("request") adds fields named
+// "http.request.<field>".
+func (n *EventNamespace) Namespace(name string) *EventNamespace {
+	return &EventNamespace{event: n.event, prefix: n.prefix + name + "."}
+}
+
+// Event returns the event this view writes to.
+func (n *EventNamespace) Event() *Event {
+	return n.event
+}
+
+// AddField adds a field to the underlying event within the namespace. See
+// Event.AddField.
+func (n *EventNamespace) AddField(key string, val interface{}) {
+	n.event.AddField(n.prefix+key, val)
+}
+
+// Add adds each field of a struct or map to the underlying event within the
+// namespace. See Event.Add.
+func (n *EventNamespace) Add(data interface{}) error {
+	return n.event.addPrefixed(n.prefix, data)
+}
+
+// AddFunc adds the fields returned by fn to the underlying event within the
+// namespace. See Event.AddFunc.
+func (n *EventNamespace) AddFunc(fn func() (string, interface{}, error)) error {
+	return n.event.AddFunc(prefixFunc(n.prefix, fn))
+}
+
+// prefixFunc wraps an AddFunc callback so the names it returns get prefix.
+func prefixFunc(prefix string, fn func() (string, interface{}, error)) func() (string, interface{}, error) {
+	return func() (string, interface{}, error) {
+		key, val, err := fn()
+		return prefix + key, val, err
+	}
+}
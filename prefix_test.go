@@ -0,0 +1,63 @@
+package libhoney
+
+import (
+	"io"
+	"testing"
+)
+
+func TestBuilderWithPrefix(t *testing.T) {
+	resetPackageVars()
+	b := NewBuilder()
+	db := b.WithPrefix("db.")
+	testEquals(t, db.Builder(), b)
+	testEquals(t, db.Prefix(), "db.")
+
+	db.AddField("rows", 3)
+	testOK(t, db.Add(map[string]interface{}{"error": "timeout"}))
+	testOK(t, db.Add(&struct {
+		Table string `json:"table"`
+	}{"users"}))
+	i := 0
+	testOK(t, db.AddFunc(func() (string, interface{}, error) {
+		if i > 0 {
+			return "", nil, io.EOF
+		}
+		i++
+		return "func", true, nil
+	}))
+	testOK(t, db.WithPrefix("pool.").AddDynamicField("size", func() interface{} { return 5 }))
+	b.AddField("error", nil)
+
+	ev := b.NewEvent()
+	testEquals(t, map[string]interface{}(ev.data), map[string]interface{}{
+		"db.rows":      3,
+		"db.error":     "timeout",
+		"db.table":     "users",
+		"db.func":      true,
+		"db.pool.size": 5,
+		"error":        nil,
+	})
+}
+
+func TestEventNamespace(t *testing.T) {
+	resetPackageVars()
+	ev := NewEvent()
+	http := ev.Namespace("http")
+	testEquals(t, http.Event(), ev)
+
+	http.AddField("status", 200)
+	testOK(t, http.Namespace("request").Add(map[string]string{"method": "GET"}))
+	testOK(t, http.AddFunc(func() (string, interface{}, error) {
+		return "", nil, io.EOF
+	}))
+	testEquals(t, map[string]interface{}(ev.data), map[string]interface{}{
+		"http.status":         200,
+		"http.request.method": "GET",
+	})
+
+	// the view respects the event's sent state
+	ev.sent = true
+	http.AddField("late", 1)
+	testOK(t, http.Add(map[string]int{"late": 1}))
+	testEquals(t, len(ev.data), 2)
+}
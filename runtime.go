@@ -0,0 +1,152 @@
+package libhoney
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/internal/logging"
+)
+
+// DefaultRuntimeMetricsInterval is how often runtime metrics are sent if
+// RuntimeMetricsConfig.Interval is not set.
+const DefaultRuntimeMetricsInterval = 10 * time.Second
+
+// processStart approximates the process start time for the uptime field.
+var processStart = time.Now()
+
+// RuntimeMetricsConfig configures the heartbeat events sent by
+// Client.StartRuntimeMetrics.
+type RuntimeMetricsConfig struct {
+	// Dataset is the dataset to send runtime events to. If empty, the client's
+	// default dataset is used.
+	Dataset string
+
+	// Interval is how often to send an event. Defaults to
+	// DefaultRuntimeMetricsInterval.
+	Interval time.Duration
+}
+
+// StartRuntimeMetrics begins sending an event describing the Go runtime (heap,
+// goroutines, GC pauses and so on) and the state of the client's send queue
+// at a fixed interval. The collector is stopped by Close. It returns an error
+// if runtime metrics are already being collected for this client.
+func (c *Client) StartRuntimeMetrics(conf RuntimeMetricsConfig) error {
+	c.ensureTransmission()
+	c.ensureBuilder()
+	if conf.Interval <= 0 {
+		conf.Interval = DefaultRuntimeMetricsInterval
+	}
+
+	c.runtimeLock.Lock()
+	defer c.runtimeLock.Unlock()
+	if c.runtimeStop != nil {
+		return errors.New("runtime metrics are already being collected")
+	}
+
+	b := c.NewBuilder()
+	if conf.Dataset != "" {
+		b.Dataset = conf.Dataset
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	c.runtimeStop = stop
+	c.runtimeDone = done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ev := b.NewEvent()
+				ev.SampleRate = 1
+				ev.Add(c.runtimeMetrics())
+				if err := ev.SendPresampled(); err != nil {
+					logging.Warn(c.logger, "failed to send runtime metrics", "err", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// stopRuntimeMetrics stops the collector started by StartRuntimeMetrics, if
+// any, and waits for it to exit.
+func (c *Client) stopRuntimeMetrics() {
+	c.runtimeLock.Lock()
+	stop, done := c.runtimeStop, c.runtimeDone
+	c.runtimeStop, c.runtimeDone = nil, nil
+	c.runtimeLock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (c *Client) runtimeMetrics() map[string]interface{} {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	data := map[string]interface{}{
+		"runtime.goroutines":      runtime.NumGoroutine(),
+		"runtime.heap_inuse":      mem.HeapInuse,
+		"runtime.heap_alloc":      mem.HeapAlloc,
+		"runtime.num_gc":          mem.NumGC,
+		"runtime.cgo_calls":       runtime.NumCgoCall(),
+		"runtime.uptime_sec":      time.Since(processStart).Seconds(),
+		"runtime.gc_pause_p50_ms": float64(0),
+		"runtime.gc_pause_p95_ms": float64(0),
+		"runtime.gc_pause_p99_ms": float64(0),
+		"runtime.gc_pause_max_ms": float64(0),
+		"runtime.gc_cpu_percent":  mem.GCCPUFraction * 100,
+	}
+	if pauses := recentGCPauses(&mem); len(pauses) > 0 {
+		data["runtime.gc_pause_p50_ms"] = percentileMs(pauses, 0.50)
+		data["runtime.gc_pause_p95_ms"] = percentileMs(pauses, 0.95)
+		data["runtime.gc_pause_p99_ms"] = percentileMs(pauses, 0.99)
+		data["runtime.gc_pause_max_ms"] = percentileMs(pauses, 1)
+	}
+
+	if _, ok := c.transmission.(statsSender); ok {
+		stats := c.Stats()
+		data["libhoney.queue_length"] = stats.QueueLength
+		var total int64
+		for reason, n := range stats.Dropped {
+			data["libhoney.dropped."+reason] = n
+			total += n
+		}
+		data["libhoney.dropped"] = total
+	}
+	return data
+}
+
+// recentGCPauses returns the pause durations recorded in mem's circular
+// buffer, sorted ascending.
+func recentGCPauses(mem *runtime.MemStats) []uint64 {
+	n := int(mem.NumGC)
+	if n > len(mem.PauseNs) {
+		n = len(mem.PauseNs)
+	}
+	pauses := make([]uint64, n)
+	copy(pauses, mem.PauseNs[:n])
+	sort.Slice(pauses, func(i, j int) bool { return pauses[i] < pauses[j] })
+	return pauses
+}
+
+// percentileMs returns the pth percentile (0 < p <= 1) of sorted nanosecond
+// durations, in milliseconds.
+func percentileMs(sorted []uint64, p float64) float64 {
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return float64(sorted[idx]) / float64(time.Millisecond)
+}
@@ -0,0 +1,72 @@
+package libhoney
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+func TestRuntimeMetrics(t *testing.T) {
+	mock := &transmission.MockSender{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "foo",
+		Dataset:      "app",
+		Transmission: mock,
+	})
+	testOK(t, err)
+
+	err = c.StartRuntimeMetrics(RuntimeMetricsConfig{
+		Dataset:  "runtime",
+		Interval: time.Millisecond,
+	})
+	testOK(t, err)
+	testErr(t, c.StartRuntimeMetrics(RuntimeMetricsConfig{}))
+
+	deadline := time.Now().Add(time.Second)
+	for len(mock.Events()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	c.Close()
+	count := len(mock.Events())
+
+	evs := mock.Events()
+	if len(evs) == 0 {
+		t.Fatal("expected at least one runtime metrics event")
+	}
+	testEquals(t, evs[0].Dataset, "runtime")
+	if _, ok := evs[0].Data["runtime.goroutines"]; !ok {
+		t.Error("expected runtime.goroutines field")
+	}
+	if _, ok := evs[0].Data["runtime.heap_inuse"]; !ok {
+		t.Error("expected runtime.heap_inuse field")
+	}
+
+	// no more events once the client is closed
+	time.Sleep(5 * time.Millisecond)
+	testEquals(t, len(mock.Events()), count)
+}
+
+func TestRuntimeMetricsQueueStats(t *testing.T) {
+	tx := &transmission.Honeycomb{
+		MaxBatchSize:         DefaultMaxBatchSize,
+		BatchTimeout:         DefaultBatchTimeout,
+		MaxConcurrentBatches: DefaultMaxConcurrentBatches,
+		PendingWorkCapacity:  DefaultPendingWorkCapacity,
+	}
+	c, err := NewClient(ClientConfig{Transmission: tx})
+	testOK(t, err)
+	defer c.Close()
+
+	data := c.runtimeMetrics()
+	testEquals(t, data["libhoney.queue_length"], 0)
+	testEquals(t, data["libhoney.dropped"], int64(0))
+	testEquals(t, data["libhoney.dropped.queue_overflow"], int64(0))
+}
+
+func TestPercentileMs(t *testing.T) {
+	pauses := []uint64{1e6, 2e6, 3e6, 4e6}
+	testEquals(t, percentileMs(pauses, 0.5), 2.0)
+	testEquals(t, percentileMs(pauses, 1), 4.0)
+	testEquals(t, percentileMs(pauses[:1], 0.99), 1.0)
+}
@@ -0,0 +1,233 @@
+package libhoney
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/honeycombio/libhoney-go/internal/logging"
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+// FieldType is the type a field is declared to have in a Builder's schema.
+type FieldType int
+
+// The field types that can be declared with Builder.DeclareSchema. Int and
+// float fields accept any Go integer or float kind respectively; float fields
+// also accept integers.
+const (
+	FieldTypeString FieldType = iota + 1
+	FieldTypeInt
+	FieldTypeFloat
+	FieldTypeBool
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case FieldTypeString:
+		return "string"
+	case FieldTypeInt:
+		return "int"
+	case FieldTypeFloat:
+		return "float"
+	case FieldTypeBool:
+		return "bool"
+	}
+	return fmt.Sprintf("FieldType(%d)", int(t))
+}
+
+// SchemaMode controls what happens when a field's value doesn't match its
+// declared type.
+type SchemaMode int
+
+const (
+	// SchemaWarn keeps the value as it is and reports the violation.
+	SchemaWarn SchemaMode = iota
+	// SchemaCoerce converts the value to the declared type (e.g. "200" to 200
+	// for an int field). Values that can't be converted are dropped. Either
+	// way the violation is reported.
+	SchemaCoerce
+	// SchemaReject drops the value and reports the violation.
+	SchemaReject
+)
+
+// SchemaOptions configures how a Builder enforces its schema.
+type SchemaOptions struct {
+	// Mode is applied to fields whose values don't match their declared type.
+	Mode SchemaMode
+
+	// DropUndeclared silently drops fields that aren't in the schema.
+	DropUndeclared bool
+
+	// ReportToResponses sends each violation down the client's responses
+	// channel (as a Response whose Err is a *SchemaViolation) instead of
+	// logging it. Note that this adds responses that don't correspond to a
+	// sent event.
+	ReportToResponses bool
+}
+
+// SchemaViolation describes a field whose value didn't match its declared
+// type.
+type SchemaViolation struct {
+	Field    string
+	Expected FieldType
+	// Actual is the Go type of the value that was added.
+	Actual string
+	// Action is what was done with the value: "kept", "coerced" or "dropped".
+	Action string
+}
+
+func (v *SchemaViolation) Error() string {
+	return fmt.Sprintf("field %q should be %s but got %s; value %s", v.Field, v.Expected, v.Actual, v.Action)
+}
+
+// fieldSchema is a declared schema shared (read-only) by a builder, its clones
+// and the events created from them.
+type fieldSchema struct {
+	fields map[string]FieldType
+	opts   SchemaOptions
+	report func(*SchemaViolation)
+}
+
+// DeclareSchema declares the types of fields on events created from this
+// builder (and from builders cloned from it afterwards). Fields added with
+// AddField, Add, AddFunc or dynamic fields are checked against the schema,
+// and mismatches are handled according to opts.Mode. Calling DeclareSchema
+// again replaces the schema; a nil or empty map with DropUndeclared unset
+// removes it. Fields already on the builder aren't rechecked.
+func (b *Builder) DeclareSchema(fields map[string]FieldType, opts SchemaOptions) {
+	var s *fieldSchema
+	if len(fields) > 0 || opts.DropUndeclared {
+		declared := make(map[string]FieldType, len(fields))
+		for k, v := range fields {
+			declared[k] = v
+		}
+		client := b.client
+		if client == nil {
+			client = &Client{}
+		}
+		s = &fieldSchema{
+			fields: declared,
+			opts:   opts,
+			report: func(v *SchemaViolation) {
+				if opts.ReportToResponses {
+					client.ensureTransmission()
+					client.transmission.SendResponse(transmission.Response{Err: v})
+					return
+				}
+				client.ensureLogger()
+				logging.Warn(client.logger, "schema violation", "field", v.Field,
+					"expected", v.Expected, "actual", v.Actual, "action", v.Action)
+			},
+		}
+	}
+	b.lock.Lock()
+	b.schema = s
+	b.lock.Unlock()
+}
+
+// check returns the value to store for key, whether to store it at all, and
+// the violation to report if val doesn't match the schema.
+func (s *fieldSchema) check(key string, val interface{}) (interface{}, bool, *SchemaViolation) {
+	want, declared := s.fields[key]
+	if !declared {
+		return val, !s.opts.DropUndeclared, nil
+	}
+	if val == nil || matchesFieldType(want, val) {
+		return val, true, nil
+	}
+
+	v := &SchemaViolation{
+		Field:    key,
+		Expected: want,
+		Actual:   reflect.TypeOf(val).String(),
+	}
+	keep := true
+	switch s.opts.Mode {
+	case SchemaWarn:
+		v.Action = "kept"
+	case SchemaCoerce:
+		if coerced, ok := coerceFieldType(want, val); ok {
+			v.Action = "coerced"
+			val = coerced
+		} else {
+			v.Action = "dropped"
+			keep = false
+		}
+	default:
+		v.Action = "dropped"
+		keep = false
+	}
+	return val, keep, v
+}
+
+// schemaViolations collects the violations found while a field lock is held.
+// They are reported after it is released, since reporting to the responses
+// channel can block.
+type schemaViolations struct {
+	schema *fieldSchema
+	found  []*SchemaViolation
+}
+
+func (v *schemaViolations) report() {
+	for _, violation := range v.found {
+		v.schema.report(violation)
+	}
+}
+
+func matchesFieldType(t FieldType, val interface{}) bool {
+	switch reflect.TypeOf(val).Kind() {
+	case reflect.String:
+		return t == FieldTypeString
+	case reflect.Bool:
+		return t == FieldTypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return t == FieldTypeInt || t == FieldTypeFloat
+	case reflect.Float32, reflect.Float64:
+		return t == FieldTypeFloat
+	}
+	return false
+}
+
+// coerceFieldType converts val to t, returning false if that isn't possible
+// without losing information.
+func coerceFieldType(t FieldType, val interface{}) (interface{}, bool) {
+	rv := reflect.ValueOf(val)
+	switch t {
+	case FieldTypeString:
+		return fmt.Sprint(val), true
+	case FieldTypeInt:
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			if f == float64(int64(f)) {
+				return int64(f), true
+			}
+		case reflect.String:
+			if i, err := strconv.ParseInt(rv.String(), 10, 64); err == nil {
+				return i, true
+			}
+		case reflect.Bool:
+			if rv.Bool() {
+				return int64(1), true
+			}
+			return int64(0), true
+		}
+	case FieldTypeFloat:
+		switch rv.Kind() {
+		case reflect.String:
+			if f, err := strconv.ParseFloat(rv.String(), 64); err == nil {
+				return f, true
+			}
+		}
+	case FieldTypeBool:
+		switch rv.Kind() {
+		case reflect.String:
+			if b, err := strconv.ParseBool(rv.String()); err == nil {
+				return b, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,211 @@
+package libhoney
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+func newSchemaTestClient(t *testing.T) (*Client, *recordingLogger) {
+	l := &recordingLogger{}
+	c, err := NewClient(ClientConfig{
+		APIKey:       "schema",
+		Dataset:      "schema",
+		Transmission: &transmission.MockSender{},
+		Logger:       l,
+	})
+	testOK(t, err)
+	return c, l
+}
+
+var testSchema = map[string]FieldType{
+	"status":   FieldTypeInt,
+	"duration": FieldTypeFloat,
+	"path":     FieldTypeString,
+	"ok":       FieldTypeBool,
+}
+
+func TestSchemaWarn(t *testing.T) {
+	c, l := newSchemaTestClient(t)
+	b := c.NewBuilder()
+	b.DeclareSchema(testSchema, SchemaOptions{Mode: SchemaWarn})
+
+	ev := b.NewEvent()
+	ev.AddField("status", "200")
+	ev.AddField("duration", 12) // ints are fine in float fields
+	ev.AddField("path", "/")
+	ev.AddField("other", struct{}{})
+
+	testEquals(t, ev.data["status"], "200")
+	testEquals(t, ev.data["duration"], 12)
+	testEquals(t, len(ev.data), 4)
+	testEquals(t, l.lines, []string{
+		"WARN schema violation field=status expected=int actual=string action=kept",
+	})
+}
+
+func TestSchemaCoerce(t *testing.T) {
+	c, l := newSchemaTestClient(t)
+	b := c.NewBuilder()
+	b.DeclareSchema(testSchema, SchemaOptions{Mode: SchemaCoerce})
+
+	ev := b.NewEvent()
+	testOK(t, ev.Add(map[string]interface{}{
+		"status":   "200",
+		"duration": "1.5",
+		"path":     42,
+		"ok":       "true",
+	}))
+	ev.AddField("status", "not a number")
+	ev.AddField("duration", nil) // nil is never a violation
+
+	testEquals(t, ev.data["status"], int64(200))
+	testEquals(t, ev.data["duration"], nil)
+	testEquals(t, ev.data["path"], "42")
+	testEquals(t, ev.data["ok"], true)
+	testEquals(t, len(l.lines), 5)
+	testEquals(t, l.lines[4], "WARN schema violation field=status expected=int actual=string action=dropped")
+
+	for _, tc := range []struct {
+		typ  FieldType
+		in   interface{}
+		out  interface{}
+		okay bool
+	}{
+		{FieldTypeInt, 3.0, int64(3), true},
+		{FieldTypeInt, 3.5, nil, false},
+		{FieldTypeInt, true, int64(1), true},
+		{FieldTypeFloat, "x", nil, false},
+		{FieldTypeFloat, false, nil, false},
+		{FieldTypeBool, 1, nil, false},
+		{FieldTypeString, 2.5, "2.5", true},
+	} {
+		out, ok := coerceFieldType(tc.typ, tc.in)
+		testEquals(t, ok, tc.okay, fmt.Sprint(tc))
+		testEquals(t, out, tc.out, fmt.Sprint(tc))
+	}
+}
+
+func TestSchemaReject(t *testing.T) {
+	c, l := newSchemaTestClient(t)
+	b := c.NewBuilder()
+	b.DeclareSchema(testSchema, SchemaOptions{Mode: SchemaReject})
+
+	type status int
+	ev := b.NewEvent()
+	testOK(t, ev.Add(struct {
+		Status   status  `json:"status"`
+		Duration float32 `json:"duration"`
+		Path     []byte  `json:"path"`
+	}{200, 1.5, []byte("/")}))
+
+	testEquals(t, ev.data["status"], status(200))
+	testEquals(t, ev.data["duration"], float32(1.5))
+	_, ok := ev.data["path"]
+	testEquals(t, ok, false)
+	testEquals(t, l.lines, []string{
+		"WARN schema violation field=path expected=string actual=[]uint8 action=dropped",
+	})
+}
+
+func TestSchemaDropUndeclared(t *testing.T) {
+	c, l := newSchemaTestClient(t)
+	b := c.NewBuilder()
+	b.DeclareSchema(testSchema, SchemaOptions{DropUndeclared: true})
+
+	b.AddField("other", 1)
+	b.AddField("path", "/")
+	testOK(t, b.AddDynamicField("dyn", func() interface{} { return 1 }))
+	testOK(t, b.AddDynamicField("ok", func() interface{} { return true }))
+
+	ev := b.NewEvent()
+	testEquals(t, map[string]interface{}(ev.data), map[string]interface{}{"path": "/", "ok": true})
+	testEquals(t, len(l.lines), 0)
+
+	// clones share the schema; replacing it on one doesn't affect the other
+	clone := b.Clone()
+	b.DeclareSchema(nil, SchemaOptions{})
+	clone.AddField("other", 1)
+	b.AddField("other", 1)
+	_, ok := clone.data["other"]
+	testEquals(t, ok, false)
+	testEquals(t, b.data["other"], 1)
+}
+
+func TestSchemaReportToResponses(t *testing.T) {
+	c, l := newSchemaTestClient(t)
+	b := c.NewBuilder()
+	b.DeclareSchema(testSchema, SchemaOptions{Mode: SchemaReject, ReportToResponses: true})
+
+	ev := b.NewEvent()
+	ev.AddField("ok", "yes")
+
+	r := <-c.TxResponses()
+	violation, ok := r.Err.(*SchemaViolation)
+	testEquals(t, ok, true)
+	testEquals(t, *violation, SchemaViolation{
+		Field:    "ok",
+		Expected: FieldTypeBool,
+		Actual:   "string",
+		Action:   "dropped",
+	})
+	testEquals(t, r.Err.Error(), `field "ok" should be bool but got string; value dropped`)
+	testEquals(t, len(l.lines), 0)
+}
+
+// blockingResponseSender blocks in SendResponse until released.
+type blockingResponseSender struct {
+	transmission.MockSender
+	sending chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingResponseSender) SendResponse(transmission.Response) bool {
+	s.sending <- struct{}{}
+	<-s.release
+	return false
+}
+
+func TestSchemaReportDoesNotHoldFieldLock(t *testing.T) {
+	sender := &blockingResponseSender{sending: make(chan struct{}), release: make(chan struct{})}
+	c, err := NewClient(ClientConfig{APIKey: "schema", Dataset: "schema", Transmission: sender})
+	testOK(t, err)
+	b := c.NewBuilder()
+	b.DeclareSchema(testSchema, SchemaOptions{Mode: SchemaReject, ReportToResponses: true})
+	ev := b.NewEvent()
+	ev.AddField("status", 200)
+
+	done := make(chan struct{})
+	go func() {
+		ev.AddField("ok", "yes")
+		close(done)
+	}()
+	<-sender.sending
+	// the violation is being reported; the event's fields are still usable
+	testEquals(t, ev.Fields(), map[string]interface{}{"status": 200})
+	close(sender.release)
+	<-done
+}
+
+func TestSchemaReportDoesNotHoldBuilderLocks(t *testing.T) {
+	sender := &blockingResponseSender{sending: make(chan struct{}), release: make(chan struct{})}
+	c, err := NewClient(ClientConfig{APIKey: "schema", Dataset: "schema", Transmission: sender})
+	testOK(t, err)
+	b := c.NewBuilder()
+	b.DeclareSchema(testSchema, SchemaOptions{Mode: SchemaReject, ReportToResponses: true})
+	b.AddDynamicField("ok", func() interface{} { return "yes" })
+
+	done := make(chan *Event)
+	go func() {
+		done <- b.NewEvent()
+	}()
+	<-sender.sending
+	// the dynamic field's violation is being reported; the builder's fields
+	// and dynamic fields can still be changed
+	b.AddField("status", 200)
+	b.AddDynamicField("path", func() interface{} { return "/" })
+	testEquals(t, b.Fields(), map[string]interface{}{"status": 200})
+	close(sender.release)
+	testEquals(t, (<-done).Fields(), map[string]interface{}{})
+}
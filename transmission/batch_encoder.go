@@ -0,0 +1,184 @@
+package transmission
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// BatchEncoder encodes batches of events for the Honeycomb batch API and
+// decodes the API's responses to them. Set one on the Honeycomb sender to
+// replace the built-in JSON and msgpack encoders, for instance with a faster
+// JSON codec. Implementations must be safe for concurrent use: batches for
+// different destinations are encoded in parallel.
+type BatchEncoder interface {
+	// ContentType is the Content-Type of encoded batches.
+	ContentType() string
+
+	// NewBatchWriter starts encoding a new batch.
+	NewBatchWriter() BatchWriter
+
+	// DecodeResponses decodes the body of a successful batch request into one
+	// Response per event sent, in order. contentType is the Content-Type of
+	// the API's response, which may differ from the request's.
+	DecodeResponses(contentType string, body io.Reader) ([]Response, error)
+}
+
+// BatchWriter encodes one batch. The sender checks the API's size limits as
+// events are added, so a BatchWriter only needs to encode and report sizes.
+type BatchWriter interface {
+	// AppendEvent encodes ev as the next event in the batch and returns its
+	// encoded size, not counting any separator. If it returns an error the
+	// batch must be left as it was.
+	AppendEvent(ev *Event) (int, error)
+
+	// Rewind removes the event added by the last successful AppendEvent. It
+	// is only called once between calls to AppendEvent.
+	Rewind()
+
+	// Len is the size the batch would be if finished now.
+	Len() int
+
+	// Finish completes the batch and returns it. The BatchWriter isn't used
+	// afterwards.
+	Finish() []byte
+}
+
+// NewJSONBatchEncoder returns the built-in BatchEncoder for JSON, which
+// applies policy to field names.
+func NewJSONBatchEncoder(policy KeyPolicy) BatchEncoder {
+	return jsonBatchEncoder{policy: policy}
+}
+
+// NewMsgpackBatchEncoder returns the built-in BatchEncoder for msgpack, which
+// applies policy to field names.
+func NewMsgpackBatchEncoder(policy KeyPolicy) BatchEncoder {
+	return msgpackBatchEncoder{policy: policy}
+}
+
+// decodeResponses decodes a batch response according to its content type. The
+// API answers msgpack requests in msgpack, and everything else in JSON.
+func decodeResponses(contentType string, body io.Reader) ([]Response, error) {
+	var responses []Response
+	var err error
+	if contentType == "application/msgpack" {
+		err = msgpack.NewDecoder(body).Decode(&responses)
+	} else {
+		err = json.NewDecoder(body).Decode(&responses)
+	}
+	return responses, err
+}
+
+type jsonBatchEncoder struct {
+	policy KeyPolicy
+}
+
+func (e jsonBatchEncoder) ContentType() string { return "application/json" }
+
+func (e jsonBatchEncoder) NewBatchWriter() BatchWriter {
+	w := &jsonBatchWriter{}
+	w.enc.buf = make([]byte, 1, 4096)
+	w.enc.buf[0] = '['
+	w.enc.policy = e.policy
+	return w
+}
+
+func (e jsonBatchEncoder) DecodeResponses(contentType string, body io.Reader) ([]Response, error) {
+	return decodeResponses(contentType, body)
+}
+
+// jsonBatchWriter builds a JSON array of events.
+type jsonBatchWriter struct {
+	enc jsonEncoder
+	n   int
+	// last is where the most recent event, with its comma, starts
+	last int
+}
+
+func (w *jsonBatchWriter) AppendEvent(ev *Event) (int, error) {
+	mark := len(w.enc.buf)
+	if w.n > 0 {
+		w.enc.buf = append(w.enc.buf, ',')
+	}
+	start := len(w.enc.buf)
+	if err := w.enc.appendEvent(ev); err != nil {
+		w.enc.buf = w.enc.buf[:mark]
+		return 0, err
+	}
+	w.last = mark
+	w.n++
+	return len(w.enc.buf) - start, nil
+}
+
+func (w *jsonBatchWriter) Rewind() {
+	w.enc.buf = w.enc.buf[:w.last]
+	w.n--
+}
+
+// Len counts the trailing ].
+func (w *jsonBatchWriter) Len() int { return len(w.enc.buf) + 1 }
+
+func (w *jsonBatchWriter) Finish() []byte {
+	return append(w.enc.buf, ']')
+}
+
+type msgpackBatchEncoder struct {
+	policy KeyPolicy
+}
+
+func (e msgpackBatchEncoder) ContentType() string { return "application/msgpack" }
+
+func (e msgpackBatchEncoder) NewBatchWriter() BatchWriter {
+	// Prepend space for largest possible msgpack array header.
+	var arrayHeader [maxMapHeader]byte
+	return &msgpackBatchWriter{
+		enc: newMsgpackEncoder(append(make([]byte, 0, 4096), arrayHeader[:]...), e.policy),
+	}
+}
+
+func (e msgpackBatchEncoder) DecodeResponses(contentType string, body io.Reader) ([]Response, error) {
+	return decodeResponses(contentType, body)
+}
+
+// msgpackBatchWriter builds a msgpack array of events.
+type msgpackBatchWriter struct {
+	enc  *msgpackEncoder
+	n    int
+	last int
+}
+
+func (w *msgpackBatchWriter) AppendEvent(ev *Event) (int, error) {
+	mark := len(w.enc.w.buf)
+	if err := w.enc.appendEvent(ev); err != nil {
+		w.enc.w.buf = w.enc.w.buf[:mark]
+		return 0, err
+	}
+	w.last = mark
+	w.n++
+	return len(w.enc.w.buf) - mark, nil
+}
+
+func (w *msgpackBatchWriter) Rewind() {
+	w.enc.w.buf = w.enc.w.buf[:w.last]
+	w.n--
+}
+
+// Len counts the largest possible array header.
+func (w *msgpackBatchWriter) Len() int { return len(w.enc.w.buf) }
+
+// Finish fills in the array header. Msgpack arrays need to be prefixed with
+// the number of elements, but we didn't know in advance how many we'd encode.
+// Also, the array header is of variable size based on array length, so we
+// need to do some []byte shenanigans to properly prepend the header.
+func (w *msgpackBatchWriter) Finish() []byte {
+	var arrayHeader [maxMapHeader]byte
+	headerBuf := bytes.NewBuffer(arrayHeader[:0])
+	msgpack.NewEncoder(headerBuf).EncodeArrayLen(w.n)
+
+	// Shenanigans. Chop off leading bytes we don't need, then copy in header.
+	byts := w.enc.w.buf[len(arrayHeader)-headerBuf.Len():]
+	copy(byts, headerBuf.Bytes())
+	return byts
+}
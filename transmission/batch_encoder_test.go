@@ -0,0 +1,128 @@
+package transmission
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// lineEncoder is a custom BatchEncoder writing one JSON event per line.
+type lineEncoder struct {
+	decoded int
+}
+
+func (e *lineEncoder) ContentType() string { return "application/x-ndjson" }
+
+func (e *lineEncoder) NewBatchWriter() BatchWriter { return &lineWriter{} }
+
+func (e *lineEncoder) DecodeResponses(contentType string, body io.Reader) ([]Response, error) {
+	e.decoded++
+	return NewJSONBatchEncoder(KeyPolicyEscape).DecodeResponses(contentType, body)
+}
+
+type lineWriter struct {
+	buf  []byte
+	last int
+}
+
+func (w *lineWriter) AppendEvent(ev *Event) (int, error) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return 0, err
+	}
+	w.last = len(w.buf)
+	w.buf = append(append(w.buf, b...), '\n')
+	return len(b), nil
+}
+
+func (w *lineWriter) Rewind()        { w.buf = w.buf[:w.last] }
+func (w *lineWriter) Len() int       { return len(w.buf) }
+func (w *lineWriter) Finish() []byte { return w.buf }
+
+func TestCustomBatchEncoder(t *testing.T) {
+	enc := &lineEncoder{}
+	frt := &FakeRoundTripper{
+		resp: &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202},{"status":202}]`)),
+		},
+	}
+	b := &batchAgg{
+		httpClient:         &http.Client{Transport: frt},
+		testNower:          &fakeNower{},
+		responses:          make(chan Response, 3),
+		metrics:            &nullMetrics{},
+		counters:           &counters{},
+		disableCompression: true,
+		// ignored in favour of the custom encoder
+		enableMsgpackEncoding: true,
+		batchEncoder:          enc,
+	}
+	events := []*Event{
+		{Data: map[string]interface{}{"a": 1}, APIHost: "http://fakeHost:8080", Metadata: 0},
+		// the shared size checks apply to custom encoders too
+		{Data: map[string]interface{}{"big": strings.Repeat("x", apiEventSizeMax)}, APIHost: "http://fakeHost:8080", Metadata: 1},
+		{Data: map[string]interface{}{"b": 2}, APIHost: "http://fakeHost:8080", Metadata: 2},
+	}
+	b.fireBatch(events)
+
+	testEquals(t, frt.req.Header.Get("Content-Type"), "application/x-ndjson")
+	testEquals(t, frt.reqBody, "{\"data\":{\"a\":1}}\n{\"data\":{\"b\":2}}\n")
+	testEquals(t, enc.decoded, 1)
+	testEquals(t, b.counters.snapshot().Dropped[DropReasonTooLarge], int64(1))
+
+	testEquals(t, len(b.responses), 3)
+	for i := 0; i < 3; i++ {
+		resp := <-b.responses
+		if resp.Metadata == 1 {
+			testErr(t, resp.Err)
+		} else {
+			testEquals(t, resp.StatusCode, 202)
+		}
+	}
+}
+
+func TestBatchWriterRewind(t *testing.T) {
+	for _, enc := range []BatchEncoder{
+		NewJSONBatchEncoder(KeyPolicyReject),
+		NewMsgpackBatchEncoder(KeyPolicyReject),
+	} {
+		w := enc.NewBatchWriter()
+		_, err := w.AppendEvent(&Event{Data: map[string]interface{}{"a": 1}})
+		testOK(t, err)
+		before := w.Len()
+		// a rejected event leaves the batch as it was
+		_, err = w.AppendEvent(&Event{Data: map[string]interface{}{"\n": 1}})
+		testErr(t, err)
+		testEquals(t, w.Len(), before)
+		_, err = w.AppendEvent(&Event{Data: map[string]interface{}{"b": 2}})
+		testOK(t, err)
+		w.Rewind()
+		testEquals(t, w.Len(), before)
+
+		reference := enc.NewBatchWriter()
+		_, err = reference.AppendEvent(&Event{Data: map[string]interface{}{"a": 1}})
+		testOK(t, err)
+		testEquals(t, w.Finish(), reference.Finish(), enc.ContentType())
+	}
+}
+
+func TestMsgpackBatchRejectsUnencodableEvents(t *testing.T) {
+	data := map[string]interface{}{"a": 1, "nil": nil, "chan": make(chan int)}
+
+	// JSON leaves the field out
+	w := NewJSONBatchEncoder(KeyPolicyEscape).NewBatchWriter()
+	_, err := w.AppendEvent(&Event{Data: data})
+	testOK(t, err)
+	testEquals(t, string(w.Finish()), `[{"data":{"a":1}}]`)
+
+	// msgpack fails the event, leaving the batch as it was
+	w = NewMsgpackBatchEncoder(KeyPolicyEscape).NewBatchWriter()
+	before := w.Len()
+	_, err = w.AppendEvent(&Event{Data: data})
+	testErr(t, err)
+	testEquals(t, w.Len(), before)
+}
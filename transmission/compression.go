@@ -0,0 +1,216 @@
+package transmission
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects how a Honeycomb sender compresses batches.
+type Compression int
+
+const (
+	// CompressionZstd compresses batches with zstd. This is the default.
+	CompressionZstd Compression = iota
+
+	// CompressionGzip compresses batches with gzip, for proxies that don't
+	// understand zstd.
+	CompressionGzip
+
+	// CompressionNone sends batches uncompressed.
+	CompressionNone
+)
+
+// Compression level 2 gives a good balance of speed and compression.
+const defaultZstdLevel = 2
+
+// compressor compresses request bodies for one sender. It is safe for
+// concurrent use.
+type compressor interface {
+	// contentEncoding is the Content-Encoding of compressed bodies.
+	contentEncoding() string
+	// compress appends the compressed form of src to dst.
+	compress(dst, src []byte) []byte
+}
+
+// newCompressor returns the compressor for c at level, which is a zstd level
+// (1-22) or a gzip level (1-9); 0 picks the codec's default. It returns nil for
+// CompressionNone.
+func newCompressor(c Compression, level int) (compressor, error) {
+	switch c {
+	case CompressionNone:
+		return nil, nil
+	case CompressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+			return nil, err
+		}
+		return &gzipCompressor{level: level}, nil
+	case CompressionZstd:
+		if level == 0 {
+			level = defaultZstdLevel
+		}
+		if level < 1 || level > 22 {
+			return nil, fmt.Errorf("invalid zstd compression level %d", level)
+		}
+		enc, err := zstd.NewWriter(
+			nil,
+			zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+			// zstd allocates 2 * GOMAXPROCS * window size, so use a small window.
+			// Most honeycomb messages are smaller than this.
+			zstd.WithWindowSize(1<<16),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &zstdCompressor{enc: enc}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %d", c)
+}
+
+// Instantiating a new encoder is expensive, so each sender keeps its own.
+// EncodeAll() is concurrency-safe.
+type zstdCompressor struct {
+	enc *zstd.Encoder
+}
+
+func (z *zstdCompressor) contentEncoding() string { return "zstd" }
+
+func (z *zstdCompressor) compress(dst, src []byte) []byte {
+	return z.enc.EncodeAll(src, dst)
+}
+
+type gzipCompressor struct {
+	level   int
+	writers sync.Pool
+}
+
+func (g *gzipCompressor) contentEncoding() string { return "gzip" }
+
+func (g *gzipCompressor) compress(dst, src []byte) []byte {
+	w := &appendWriter{buf: dst}
+	zw, _ := g.writers.Get().(*gzip.Writer)
+	if zw == nil {
+		// the level was checked by newCompressor
+		zw, _ = gzip.NewWriterLevel(w, g.level)
+	} else {
+		zw.Reset(w)
+	}
+	// writes to an appendWriter can't fail
+	zw.Write(src)
+	zw.Close()
+	g.writers.Put(zw)
+	return w.buf
+}
+
+var (
+	defaultCompressorOnce sync.Once
+	defaultZstd           compressor
+)
+
+// defaultCompressor is used by batchAggs built without a compressor of their
+// own, as in tests.
+func defaultCompressor() compressor {
+	defaultCompressorOnce.Do(func() {
+		var err error
+		defaultZstd, err = newCompressor(CompressionZstd, 0)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return defaultZstd
+}
+
+// buildBody returns the request body for an encoded batch, compressing it
+// unless compression is off or the batch is under the minimum size. The time
+// taken and the compression ratio are reported to b.metrics; sendBatch also
+// records each batch's ratio with DistributionMetrics.
+func (b *batchAgg) buildBody(encoded []byte) *requestBody {
+	if b.disableCompression || len(encoded) < b.compressMinBytes {
+		return newRequestBody(encoded, nil)
+	}
+	c := b.compressor
+	if c == nil {
+		c = defaultCompressor()
+	}
+	start := time.Now()
+	body := newRequestBody(encoded, c)
+	elapsed := time.Since(start)
+
+	b.metrics.Count("compression_time_us", int64(elapsed/time.Microsecond))
+	b.metrics.Gauge("compression_ratio", float64(len(encoded))/float64(len(body.buf)))
+	return body
+}
+
+var compressBufferPool sync.Pool
+
+// requestBody is a batch's request payload, compressed into a pooled buffer if
+// compression is on. Retries read it again, so the buffer goes back to the
+// pool only once the batch is done with it and every request's reader has
+// been closed.
+type requestBody struct {
+	buf []byte
+	// encoding is the Content-Encoding of buf, or "" if it isn't compressed
+	encoding string
+	refs     int32
+}
+
+// newRequestBody compresses encoded with c, or uses it as is if c is nil.
+func newRequestBody(encoded []byte, c compressor) *requestBody {
+	if c == nil {
+		return &requestBody{buf: encoded, refs: 1}
+	}
+	var buf []byte
+	if found, ok := compressBufferPool.Get().([]byte); ok {
+		buf = found[:0]
+	}
+	return &requestBody{
+		buf:      c.compress(buf, encoded),
+		encoding: c.contentEncoding(),
+		refs:     1,
+	}
+}
+
+// reader returns a reader over the body, which holds the buffer until it is
+// closed.
+func (r *requestBody) reader() io.ReadCloser {
+	atomic.AddInt32(&r.refs, 1)
+	return &bodyReader{Reader: bytes.NewReader(r.buf), body: r}
+}
+
+func (r *requestBody) release() {
+	if atomic.AddInt32(&r.refs, -1) == 0 && r.encoding != "" {
+		compressBufferPool.Put(r.buf[:0])
+	}
+}
+
+type bodyReader struct {
+	*bytes.Reader
+	body *requestBody
+	once sync.Once
+}
+
+func (r *bodyReader) Close() error {
+	r.once.Do(r.body.release)
+	return nil
+}
+
+// buildReqReader returns an io.Reader and a boolean, indicating whether or not
+// the io.Reader is compressed.
+func buildReqReader(jsonEncoded []byte, compress bool) (io.ReadCloser, bool) {
+	var c compressor
+	if compress {
+		c = defaultCompressor()
+	}
+	body := newRequestBody(jsonEncoded, c)
+	defer body.release()
+	return body.reader(), body.encoding != ""
+}
@@ -0,0 +1,153 @@
+package transmission
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func decompress(t *testing.T, encoding string, body []byte) []byte {
+	switch encoding {
+	case "gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		testOK(t, err)
+		out, err := ioutil.ReadAll(r)
+		testOK(t, err)
+		return out
+	case "zstd":
+		d, err := zstd.NewReader(nil)
+		testOK(t, err)
+		out, err := d.DecodeAll(body, nil)
+		testOK(t, err)
+		return out
+	}
+	return body
+}
+
+func TestCompressors(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"data":{"a":1,"b":"some text"}},`, 100))
+	for _, tc := range []struct {
+		compression Compression
+		level       int
+		encoding    string
+	}{
+		{CompressionZstd, 0, "zstd"},
+		{CompressionZstd, 1, "zstd"},
+		{CompressionZstd, 19, "zstd"},
+		{CompressionGzip, 0, "gzip"},
+		{CompressionGzip, 9, "gzip"},
+	} {
+		c, err := newCompressor(tc.compression, tc.level)
+		testOK(t, err)
+		testEquals(t, c.contentEncoding(), tc.encoding)
+		// compressing twice exercises the gzip writer pool
+		for i := 0; i < 2; i++ {
+			out := c.compress([]byte("prefix"), payload)
+			testEquals(t, string(out[:6]), "prefix")
+			testEquals(t, decompress(t, tc.encoding, out[6:]), payload)
+		}
+	}
+
+	c, err := newCompressor(CompressionNone, 0)
+	testOK(t, err)
+	testEquals(t, c, nil)
+
+	for _, tc := range []struct {
+		compression Compression
+		level       int
+	}{
+		{CompressionZstd, 23},
+		{CompressionZstd, -1},
+		{CompressionGzip, 10},
+		{Compression(99), 0},
+	} {
+		_, err := newCompressor(tc.compression, tc.level)
+		testErr(t, err)
+	}
+}
+
+// compressionMetrics records everything reported to it.
+type compressionMetrics struct {
+	recordingMetrics
+	counts map[string]interface{}
+	gauges map[string]interface{}
+}
+
+func (m *compressionMetrics) Count(name string, val interface{}) { m.counts[name] = val }
+func (m *compressionMetrics) Gauge(name string, val interface{}) { m.gauges[name] = val }
+
+func TestFireBatchCompression(t *testing.T) {
+	gz, err := newCompressor(CompressionGzip, 0)
+	testOK(t, err)
+	for _, minBytes := range []int{0, 1000} {
+		metrics := &compressionMetrics{
+			recordingMetrics: recordingMetrics{
+				timings:    map[string]interface{}{},
+				histograms: map[string]interface{}{},
+			},
+			counts: map[string]interface{}{},
+			gauges: map[string]interface{}{},
+		}
+		frt := &FakeRoundTripper{
+			resp: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202}]`)),
+			},
+		}
+		b := &batchAgg{
+			httpClient:       &http.Client{Transport: frt},
+			testNower:        &fakeNower{},
+			responses:        make(chan Response, 1),
+			metrics:          metrics,
+			compressor:       gz,
+			compressMinBytes: minBytes,
+		}
+		b.fireBatch([]*Event{
+			{Data: map[string]interface{}{"a": 1}, APIHost: "http://fakeHost:8080", APIKey: "written", Dataset: "ds1"},
+		})
+
+		encoding := frt.req.Header.Get("Content-Encoding")
+		testEquals(t, string(decompress(t, encoding, []byte(frt.reqBody))), `[{"data":{"a":1}}]`)
+		_, timed := metrics.counts["compression_time_us"]
+		_, gauged := metrics.gauges["compression_ratio"]
+		_, ratio := metrics.histograms["batch_compression_ratio"]
+		testEquals(t, ratio, true)
+		if minBytes == 0 {
+			testEquals(t, encoding, "gzip")
+			testEquals(t, []bool{timed, gauged}, []bool{true, true})
+		} else {
+			// too small to be worth compressing
+			testEquals(t, encoding, "")
+			testEquals(t, []bool{timed, gauged}, []bool{false, false})
+		}
+	}
+}
+
+func TestSendersCompressIndependently(t *testing.T) {
+	gzipped := &Honeycomb{
+		MaxBatchSize:        1,
+		PendingWorkCapacity: 1,
+		Compression:         CompressionGzip,
+		CompressionLevel:    9,
+	}
+	testOK(t, gzipped.Start())
+	defer gzipped.Stop()
+	def := &Honeycomb{PendingWorkCapacity: 1, MaxBatchSize: 1}
+	testOK(t, def.Start())
+	defer def.Stop()
+	none := &Honeycomb{PendingWorkCapacity: 1, MaxBatchSize: 1, Compression: CompressionNone}
+	testOK(t, none.Start())
+	defer none.Stop()
+
+	testEquals(t, gzipped.compressor.contentEncoding(), "gzip")
+	testEquals(t, def.compressor.contentEncoding(), "zstd")
+	testEquals(t, none.compressor, nil)
+
+	bad := &Honeycomb{PendingWorkCapacity: 1, MaxBatchSize: 1, Compression: CompressionGzip, CompressionLevel: 42}
+	testErr(t, bad.Start())
+}
@@ -5,4 +5,6 @@ type DiscardSender struct {
 	WriterSender
 }
 
-func (d *DiscardSender) Add(ev *Event) {}
+func (d *DiscardSender) Add(ev *Event) {
+	release(ev)
+}
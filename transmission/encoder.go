@@ -0,0 +1,408 @@
+package transmission
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/internal/jsonstr"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// valueEncoder writes single field values in one wire format. Each method
+// returns false if the value can't be represented. The JSON encoder then
+// leaves the field out of the event; the msgpack encoder fails the event, as
+// encoding it with the msgpack library always has.
+type valueEncoder interface {
+	// encodeNil handles nil values, including nil pointers, slices and maps
+	encodeNil() bool
+	encodeString(string) bool
+	encodeBool(bool) bool
+	encodeInt(int64) bool
+	encodeUint(uint64) bool
+	encodeFloat(f float64, bits int) bool
+	encodeTime(time.Time) bool
+	encodeBytes([]byte) bool
+	// encodeOther handles everything else, via reflection
+	encodeOther(interface{}) bool
+}
+
+// encodeValue writes v with enc, returning false if it can't be encoded. This
+// type switch is shared by every wire format so that they agree on how values
+// are sent.
+func encodeValue(enc valueEncoder, v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return enc.encodeNil()
+	case string:
+		return enc.encodeString(v)
+	case bool:
+		return enc.encodeBool(v)
+	case int:
+		return enc.encodeInt(int64(v))
+	case int8:
+		return enc.encodeInt(int64(v))
+	case int16:
+		return enc.encodeInt(int64(v))
+	case int32:
+		return enc.encodeInt(int64(v))
+	case int64:
+		return enc.encodeInt(v)
+	case uint:
+		return enc.encodeUint(uint64(v))
+	case uint8:
+		return enc.encodeUint(uint64(v))
+	case uint16:
+		return enc.encodeUint(uint64(v))
+	case uint32:
+		return enc.encodeUint(uint64(v))
+	case uint64:
+		return enc.encodeUint(v)
+	case float32:
+		return enc.encodeFloat(float64(v), 32)
+	case float64:
+		return enc.encodeFloat(v, 64)
+	case time.Duration:
+		return enc.encodeInt(int64(v))
+	case time.Time:
+		return enc.encodeTime(v)
+	case []byte:
+		if v == nil {
+			return enc.encodeNil()
+		}
+		return enc.encodeBytes(v)
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if val.IsNil() {
+			return enc.encodeNil()
+		}
+	}
+	return enc.encodeOther(v)
+}
+
+// sortedFieldKeys returns the keys of data in order, reusing scratch.
+func sortedFieldKeys(data map[string]interface{}, scratch []string) []string {
+	keys := scratch[:0]
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envelope returns the sample rate and timestamp to send with ev; zero values
+// are left out.
+func envelope(ev *Event) (uint, *time.Time) {
+	// don't include sample rate if it's 1; this is the default
+	sampleRate := ev.SampleRate
+	if sampleRate == 1 {
+		sampleRate = 0
+	}
+	if ev.Timestamp.IsZero() {
+		return sampleRate, nil
+	}
+	return sampleRate, &ev.Timestamp
+}
+
+// jsonEncoder appends events as JSON to buf, producing the same output as
+// encoding/json (with the data keys sorted) without reflecting on common field
+// types.
+type jsonEncoder struct {
+	buf  []byte
+	keys []string
+	keyFilter
+}
+
+// appendEvent appends ev's batch representation to e.buf.
+func (e *jsonEncoder) appendEvent(ev *Event) error {
+	e.buf = append(e.buf, `{"data":`...)
+	if err := e.appendFields(ev.Data); err != nil {
+		return err
+	}
+	sampleRate, timestamp := envelope(ev)
+	if sampleRate != 0 {
+		e.buf = append(e.buf, `,"samplerate":`...)
+		e.buf = strconv.AppendUint(e.buf, uint64(sampleRate), 10)
+	}
+	if timestamp != nil {
+		mark := len(e.buf)
+		e.buf = append(e.buf, `,"time":`...)
+		if !e.encodeTime(*timestamp) {
+			e.buf = e.buf[:mark]
+			return fmt.Errorf("can't encode event timestamp %v", *timestamp)
+		}
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+// appendFields appends data as a JSON object, leaving out fields whose values
+// are nil or can't be encoded. It returns an error if the key policy rejects a
+// field name.
+func (e *jsonEncoder) appendFields(data map[string]interface{}) error {
+	e.keys = sortedFieldKeys(data, e.keys)
+	e.reset()
+	e.buf = append(e.buf, '{')
+	first := true
+	for _, k := range e.keys {
+		key, ok, err := e.apply(k, data)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		mark := len(e.buf)
+		if !first {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = jsonstr.Append(e.buf, key)
+		e.buf = append(e.buf, ':')
+		if !encodeValue(e, data[k]) {
+			e.buf = e.buf[:mark]
+			continue
+		}
+		first = false
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+// encodeNil leaves nil fields out of JSON events.
+func (e *jsonEncoder) encodeNil() bool {
+	return false
+}
+
+func (e *jsonEncoder) encodeString(s string) bool {
+	e.buf = jsonstr.Append(e.buf, s)
+	return true
+}
+
+func (e *jsonEncoder) encodeBool(b bool) bool {
+	e.buf = strconv.AppendBool(e.buf, b)
+	return true
+}
+
+func (e *jsonEncoder) encodeInt(n int64) bool {
+	e.buf = strconv.AppendInt(e.buf, n, 10)
+	return true
+}
+
+func (e *jsonEncoder) encodeUint(n uint64) bool {
+	e.buf = strconv.AppendUint(e.buf, n, 10)
+	return true
+}
+
+// encodeFloat formats f as encoding/json does. JSON has no representation for
+// NaN or infinities.
+func (e *jsonEncoder) encodeFloat(f float64, bits int) bool {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return false
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	e.buf = strconv.AppendFloat(e.buf, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(e.buf)
+		if n >= 4 && e.buf[n-4] == 'e' && e.buf[n-3] == '-' && e.buf[n-2] == '0' {
+			e.buf[n-2] = e.buf[n-1]
+			e.buf = e.buf[:n-1]
+		}
+	}
+	return true
+}
+
+// encodeTime formats t as time.Time's MarshalJSON does.
+func (e *jsonEncoder) encodeTime(t time.Time) bool {
+	if y := t.Year(); y < 0 || y >= 10000 {
+		return false
+	}
+	e.buf = append(e.buf, '"')
+	e.buf = t.AppendFormat(e.buf, time.RFC3339Nano)
+	e.buf = append(e.buf, '"')
+	return true
+}
+
+// encodeBytes writes b base64-encoded, as encoding/json does.
+func (e *jsonEncoder) encodeBytes(b []byte) bool {
+	n := base64.StdEncoding.EncodedLen(len(b))
+	e.buf = append(e.buf, '"')
+	start := len(e.buf)
+	for i := 0; i < n; i++ {
+		e.buf = append(e.buf, 0)
+	}
+	base64.StdEncoding.Encode(e.buf[start:], b)
+	e.buf = append(e.buf, '"')
+	return true
+}
+
+func (e *jsonEncoder) encodeOther(v interface{}) bool {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	e.buf = append(e.buf, b...)
+	return true
+}
+
+// appendWriter lets a msgpack.Encoder append to a byte slice.
+type appendWriter struct {
+	buf []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+func (w *appendWriter) WriteByte(c byte) error {
+	w.buf = append(w.buf, c)
+	return nil
+}
+
+// msgpackEncoder appends events as msgpack to w.buf without reflecting on
+// common field types. Unlike the JSON encoder it sends nil fields, as msgpack
+// nil, and fails the whole event if a field can't be encoded.
+type msgpackEncoder struct {
+	w    appendWriter
+	enc  *msgpack.Encoder
+	keys []string
+	// err is why the last value couldn't be encoded
+	err error
+	keyFilter
+}
+
+func newMsgpackEncoder(buf []byte, policy KeyPolicy) *msgpackEncoder {
+	e := &msgpackEncoder{w: appendWriter{buf: buf}, keyFilter: keyFilter{policy: policy}}
+	e.enc = msgpack.NewEncoder(&e.w).UseJSONTag(true)
+	return e
+}
+
+// maxMapHeader is the size of the largest msgpack map or array header.
+const maxMapHeader = 5
+
+// appendEvent appends ev's batch representation to e.w.buf.
+func (e *msgpackEncoder) appendEvent(ev *Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("msgpack panic: %v, trying to encode: %#v", p, ev)
+		}
+	}()
+	sampleRate, timestamp := envelope(ev)
+	n := 1
+	if sampleRate != 0 {
+		n++
+	}
+	if timestamp != nil {
+		n++
+	}
+	e.enc.EncodeMapLen(n)
+	e.enc.EncodeString("data")
+	if err := e.appendFields(ev.Data); err != nil {
+		return err
+	}
+	if sampleRate != 0 {
+		e.enc.EncodeString("samplerate")
+		e.enc.EncodeUint64(uint64(sampleRate))
+	}
+	if timestamp != nil {
+		e.enc.EncodeString("time")
+		e.enc.EncodeTime(*timestamp)
+	}
+	return nil
+}
+
+// appendFields appends data as a msgpack map. The map's length isn't known
+// until the key policy has been applied to every field, so room is left for
+// the largest header and the entries are shifted down afterwards if a smaller
+// one will do. It returns an error if the key policy rejects a field name or a
+// value can't be encoded.
+func (e *msgpackEncoder) appendFields(data map[string]interface{}) error {
+	e.keys = sortedFieldKeys(data, e.keys)
+	e.reset()
+	start := len(e.w.buf)
+	var header [maxMapHeader]byte
+	e.w.buf = append(e.w.buf, header[:]...)
+	n := 0
+	for _, k := range e.keys {
+		key, ok, err := e.apply(k, data)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		e.enc.EncodeString(key)
+		if !encodeValue(e, data[k]) {
+			return e.err
+		}
+		n++
+	}
+
+	entries := e.w.buf[start+maxMapHeader:]
+	e.w.buf = e.w.buf[:start]
+	e.enc.EncodeMapLen(n)
+	headerLen := len(e.w.buf) - start
+	e.w.buf = e.w.buf[:start+headerLen+len(entries)]
+	copy(e.w.buf[start+headerLen:], entries)
+	return nil
+}
+
+func (e *msgpackEncoder) check(err error) bool {
+	e.err = err
+	return err == nil
+}
+
+func (e *msgpackEncoder) encodeNil() bool {
+	return e.check(e.enc.EncodeNil())
+}
+
+func (e *msgpackEncoder) encodeString(s string) bool {
+	return e.check(e.enc.EncodeString(s))
+}
+
+func (e *msgpackEncoder) encodeBool(b bool) bool {
+	return e.check(e.enc.EncodeBool(b))
+}
+
+func (e *msgpackEncoder) encodeInt(n int64) bool {
+	return e.check(e.enc.EncodeInt64(n))
+}
+
+func (e *msgpackEncoder) encodeUint(n uint64) bool {
+	return e.check(e.enc.EncodeUint64(n))
+}
+
+func (e *msgpackEncoder) encodeFloat(f float64, bits int) bool {
+	if bits == 32 {
+		return e.check(e.enc.EncodeFloat32(float32(f)))
+	}
+	return e.check(e.enc.EncodeFloat64(f))
+}
+
+func (e *msgpackEncoder) encodeTime(t time.Time) bool {
+	return e.check(e.enc.EncodeTime(t))
+}
+
+func (e *msgpackEncoder) encodeBytes(b []byte) bool {
+	return e.check(e.enc.EncodeBytes(b))
+}
+
+// encodeOther falls back to the msgpack library. A panic from it is recovered
+// by appendEvent.
+func (e *msgpackEncoder) encodeOther(v interface{}) bool {
+	return e.check(e.enc.Encode(v))
+}
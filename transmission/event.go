@@ -1,15 +1,6 @@
 package transmission
 
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"reflect"
-	"sort"
-	"time"
-
-	"github.com/vmihailenco/msgpack/v4"
-)
+import "time"
 
 type Event struct {
 	// APIKey, if set, overrides whatever is found in Config
@@ -30,109 +21,48 @@ type Event struct {
 
 	// Data contains the content of the event (all the fields and their values)
 	Data map[string]interface{}
+
+	// Release, if set, is called by the Sender once it has finished with the
+	// event: after the event's Response (if any) has been sent, and when the
+	// Sender no longer holds any reference to the event or its Data. Senders
+	// that keep events, such as MockSender, never call it.
+	Release func(*Event)
+}
+
+// release calls ev.Release, if it is set.
+func release(ev *Event) {
+	if ev.Release != nil {
+		ev.Release(ev)
+	}
 }
 
 // Marshaling an Event for batching up to the Honeycomb servers. Omits fields
 // that aren't specific to this particular event, and allows for behavior like
 // omitempty'ing a zero'ed out time.Time.
 func (e *Event) MarshalJSON() ([]byte, error) {
-	tPointer := &(e.Timestamp)
-	if e.Timestamp.IsZero() {
-		tPointer = nil
+	enc := jsonEncoder{}
+	if err := enc.appendEvent(e); err != nil {
+		return nil, err
 	}
-
-	// don't include sample rate if it's 1; this is the default
-	sampleRate := e.SampleRate
-	if sampleRate == 1 {
-		sampleRate = 0
-	}
-
-	return json.Marshal(struct {
-		Data       marshallableMap `json:"data"`
-		SampleRate uint            `json:"samplerate,omitempty"`
-		Timestamp  *time.Time      `json:"time,omitempty"`
-	}{e.Data, sampleRate, tPointer})
+	return enc.buf, nil
 }
 
-func (e *Event) MarshalMsgpack() (byts []byte, err error) {
-	tPointer := &(e.Timestamp)
-	if e.Timestamp.IsZero() {
-		tPointer = nil
+func (e *Event) MarshalMsgpack() ([]byte, error) {
+	enc := newMsgpackEncoder(nil, KeyPolicyEscape)
+	if err := enc.appendEvent(e); err != nil {
+		return nil, err
 	}
-
-	// don't include sample rate if it's 1; this is the default
-	sampleRate := e.SampleRate
-	if sampleRate == 1 {
-		sampleRate = 0
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			byts = nil
-			err = fmt.Errorf("msgpack panic: %v, trying to encode: %#v", p, e)
-		}
-	}()
-
-	var buf bytes.Buffer
-	err = msgpack.NewEncoder(&buf).UseJSONTag(true).Encode(struct {
-		Data       map[string]interface{} `msgpack:"data"`
-		SampleRate uint                   `msgpack:"samplerate,omitempty"`
-		Timestamp  *time.Time             `msgpack:"time,omitempty"`
-	}{e.Data, sampleRate, tPointer})
-	return buf.Bytes(), err
+	return enc.w.buf, nil
 }
 
 type marshallableMap map[string]interface{}
 
+// MarshalJSON writes the map's fields in sorted order, leaving out those whose
+// values are nil or can't be encoded. Field names are escaped as needed.
 func (m marshallableMap) MarshalJSON() ([]byte, error) {
-	keys := make([]string, len(m))
-	i := 0
-	for k := range m {
-		keys[i] = k
-		i++
-	}
-	sort.Strings(keys)
-	out := bytes.NewBufferString("{")
-
-	first := true
-	for _, k := range keys {
-		b, ok := maybeMarshalValue(m[k])
-		if ok {
-			if first {
-				first = false
-			} else {
-				out.WriteByte(',')
-			}
-
-			out.WriteByte('"')
-			out.Write([]byte(k))
-			out.WriteByte('"')
-			out.WriteByte(':')
-			out.Write(b)
-		}
-	}
-	out.WriteByte('}')
-	return out.Bytes(), nil
-}
-
-var (
-	ptrKinds = []reflect.Kind{reflect.Ptr, reflect.Slice, reflect.Map}
-)
-
-func maybeMarshalValue(v interface{}) ([]byte, bool) {
-	if v == nil {
-		return nil, false
-	}
-	val := reflect.ValueOf(v)
-	kind := val.Type().Kind()
-	for _, ptrKind := range ptrKinds {
-		if kind == ptrKind && val.IsNil() {
-			return nil, false
-		}
-	}
-	b, err := json.Marshal(v)
-	if err != nil {
-		return nil, false
+	enc := jsonEncoder{}
+	if err := enc.appendFields(m); err != nil {
+		return nil, err
 	}
-	return b, true
+	return enc.buf, nil
 }
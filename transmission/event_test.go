@@ -3,6 +3,9 @@ package transmission
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -67,6 +70,243 @@ func TestEventMarshal(t *testing.T) {
 	})
 }
 
+func TestEncoderMatchesEncodingJSON(t *testing.T) {
+	values := []interface{}{
+		"", "plain", "quote\" back\\slash", "<html> & co", "line\nbreak\ttab\r",
+		"\x00\x01\x1f\x7f", "unicode é 日本", "sep \u2028 \u2029",
+		true, false,
+		0, -1, int8(-128), int16(300), int32(-70000), int64(math.MaxInt64), int64(math.MinInt64),
+		uint(1), uint8(255), uint16(65535), uint32(math.MaxUint32), uint64(math.MaxUint64),
+		float32(1.5), float32(1e-7), float32(3.4e38), float32(0.1),
+		0.0, 1.0, -2.5, 1e-7, 1e21, 123456789.123, math.SmallestNonzeroFloat64, math.MaxFloat64,
+		time.Second, time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC),
+		time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("x", 3600)),
+		[]byte{}, []byte("hello, world"),
+		[]int{1, 2}, map[string]int{"x": 1}, struct{ A string }{"a"},
+	}
+	for _, v := range values {
+		expected, err := json.Marshal(v)
+		testOK(t, err)
+		enc := jsonEncoder{}
+		testEquals(t, encodeValue(&enc, v), true, fmt.Sprintf("%T %v", v, v))
+		testEquals(t, string(enc.buf), string(expected), fmt.Sprintf("%T %v", v, v))
+	}
+
+	// encoding/json has written invalid UTF-8 both as a \ufffd escape and as a
+	// literal U+FFFD, depending on the Go version; both decode the same
+	enc := jsonEncoder{}
+	encodeValue(&enc, "bad utf8 \xff\xfe")
+	testEquals(t, string(enc.buf), `"bad utf8 \ufffd\ufffd"`)
+}
+
+func TestEncoderSkipsUnencodableFields(t *testing.T) {
+	var nilPtr *int
+	var nilMap map[string]int
+	data := map[string]interface{}{
+		"a":        1,
+		"nil":      nil,
+		"nilptr":   nilPtr,
+		"nilmap":   nilMap,
+		"nilbytes": []byte(nil),
+		"nan":      math.NaN(),
+		"inf":      math.Inf(1),
+		"chan":     make(chan int),
+		"year":     time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC),
+		"z":        "last",
+	}
+	b, err := json.Marshal(&Event{Data: data})
+	testOK(t, err)
+	testEquals(t, string(b), `{"data":{"a":1,"z":"last"}}`)
+
+	b, err = json.Marshal(marshallableMap{"nil": nil, "inf": math.Inf(-1)})
+	testOK(t, err)
+	testEquals(t, string(b), `{}`)
+
+	// msgpack fails the whole event on a field it can't encode...
+	_, err = msgpack.Marshal(&Event{Data: data})
+	testErr(t, err)
+
+	// ...and otherwise sends every field, nil ones as nil; it can encode NaN,
+	// infinities and large years
+	delete(data, "chan")
+	b, err = msgpack.Marshal(&Event{Data: data})
+	testOK(t, err)
+	var decoded map[string]interface{}
+	testOK(t, msgpack.Unmarshal(b, &decoded))
+	fields := decoded["data"].(map[string]interface{})
+	testEquals(t, len(fields), 9)
+	testEquals(t, fields["a"], int64(1))
+	testEquals(t, fields["z"], "last")
+	for _, k := range []string{"nil", "nilptr", "nilmap", "nilbytes"} {
+		v, ok := fields[k]
+		testEquals(t, ok, true, k)
+		testEquals(t, v, nil, k)
+	}
+}
+
+func TestEncoderEscapesKeys(t *testing.T) {
+	e := &Event{Data: map[string]interface{}{
+		`quote"key`:   1,
+		"new\nline":   2,
+		"<tag>":       3,
+		"bad\xffutf8": 4,
+	}}
+	b, err := json.Marshal(e)
+	testOK(t, err)
+	testEquals(t, json.Valid(b), true, string(b))
+	var decoded struct {
+		Data map[string]int `json:"data"`
+	}
+	testOK(t, json.Unmarshal(b, &decoded))
+	testEquals(t, decoded.Data, map[string]int{
+		`quote"key`:     1,
+		"new\nline":     2,
+		"<tag>":         3,
+		"bad\ufffdutf8": 4,
+	})
+}
+
+func TestKeyPolicies(t *testing.T) {
+	data := map[string]interface{}{
+		"ok":        1,
+		`a"b`:       2,
+		"a_b":       3,
+		"c\\d":      4,
+		"c\x00d":    5,
+		"bad\xff":   6,
+		"tab\there": 7,
+	}
+	b := &batchAgg{keyPolicy: KeyPolicySanitize}
+	enc := jsonEncoder{keyFilter: keyFilter{policy: KeyPolicySanitize}}
+	testOK(t, enc.appendFields(data))
+	var decoded map[string]int
+	testOK(t, json.Unmarshal(enc.buf, &decoded))
+	// a"b loses to the existing a_b; c\x00d sorts first, so c\\d loses to it
+	testEquals(t, decoded, map[string]int{
+		"ok":       1,
+		"a_b":      3,
+		"c_d":      5,
+		"bad_":     6,
+		"tab_here": 7,
+	})
+
+	enc = jsonEncoder{keyFilter: keyFilter{policy: KeyPolicyReject}}
+	err := enc.appendFields(data)
+	testEquals(t, err, &InvalidKeyError{Key: `a"b`})
+	testEquals(t, err.Error(), `invalid field name "a\"b"`)
+
+	for _, msgpack := range []bool{false, true} {
+		b = &batchAgg{
+			keyPolicy:             KeyPolicyReject,
+			enableMsgpackEncoding: msgpack,
+			responses:             make(chan Response, 1),
+			counters:              &counters{},
+		}
+		events := []*Event{
+			{Data: map[string]interface{}{"ok": 1}},
+			{Data: data, Metadata: "bad"},
+		}
+		_, numEncoded := b.encodeBatch(b.encoder(), events)
+		testEquals(t, numEncoded, 1)
+		testEquals(t, events[1], (*Event)(nil))
+		resp := <-b.responses
+		testEquals(t, resp.Metadata, "bad")
+		_, ok := resp.Err.(*InvalidKeyError)
+		testEquals(t, ok, true)
+		testEquals(t, b.counters.snapshot().Dropped[DropReasonInvalidKey], int64(1))
+	}
+}
+
+// randomKey returns a short string drawn mostly from characters that need
+// escaping in JSON, including invalid UTF-8.
+func randomKey(r *rand.Rand) string {
+	const alphabet = "ab_\"\\\x00\x01\n\t\x7f<>&\xff\xc3\xa9\xe2\x80\xa8"
+	b := make([]byte, r.Intn(8))
+	for i := range b {
+		b[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+func randomValue(r *rand.Rand) interface{} {
+	switch r.Intn(8) {
+	case 0:
+		return randomKey(r)
+	case 1:
+		return r.Int63() - r.Int63()
+	case 2:
+		return r.NormFloat64() * math.Pow(10, float64(r.Intn(60)-30))
+	case 3:
+		return r.Intn(2) == 0
+	case 4:
+		return []byte(randomKey(r))
+	case 5:
+		return time.Unix(r.Int63n(1<<40), r.Int63n(1e9))
+	case 6:
+		return map[string]interface{}{randomKey(r): randomKey(r)}
+	default:
+		return nil
+	}
+}
+
+// TestRandomBatchesAreValidJSON encodes batches of randomly named and valued
+// fields under each key policy, checking that every batch is valid JSON and
+// every event is either sent or answered.
+func TestRandomBatchesAreValidJSON(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, policy := range []KeyPolicy{KeyPolicyEscape, KeyPolicySanitize, KeyPolicyReject} {
+		for round := 0; round < 200; round++ {
+			events := make([]*Event, 1+r.Intn(10))
+			for i := range events {
+				data := make(map[string]interface{})
+				for n := r.Intn(6); n > 0; n-- {
+					data[randomKey(r)] = randomValue(r)
+				}
+				events[i] = &Event{Data: data, SampleRate: uint(r.Intn(3))}
+			}
+			b := &batchAgg{
+				keyPolicy: policy,
+				responses: make(chan Response, len(events)),
+			}
+			encoded, numEncoded := b.encodeBatch(b.encoder(), events)
+			msg := fmt.Sprintf("policy %d round %d: %q", policy, round, encoded)
+			testEquals(t, json.Valid(encoded), true, msg)
+			var decoded []map[string]interface{}
+			testEquals(t, json.Unmarshal(encoded, &decoded), nil, msg)
+			testEquals(t, len(decoded), numEncoded, msg)
+			testEquals(t, numEncoded+len(b.responses), len(events), msg)
+			if policy != KeyPolicyReject {
+				testEquals(t, numEncoded, len(events), msg)
+			}
+		}
+	}
+}
+
+func TestEncoderMsgpackMapHeaders(t *testing.T) {
+	// map headers grow past fixmap (15 entries) and map16 (65535 entries)
+	for _, n := range []int{0, 1, 15, 16, 65535, 65536} {
+		data := make(map[string]interface{}, n+1)
+		for i := 0; i < n; i++ {
+			data[fmt.Sprintf("k%d", i)] = i
+		}
+		// a field dropped by the key policy mustn't be counted
+		data["k_"] = -1
+		data["k\n"] = -2
+		enc := newMsgpackEncoder(nil, KeyPolicySanitize)
+		testOK(t, enc.appendEvent(&Event{Data: data}))
+		var decoded struct {
+			Data map[string]int64 `msgpack:"data"`
+		}
+		testOK(t, msgpack.Unmarshal(enc.w.buf, &decoded))
+		testEquals(t, decoded.Data["k_"], int64(-1))
+		delete(decoded.Data, "k_")
+		testEquals(t, len(decoded.Data), n)
+		if n > 0 {
+			testEquals(t, decoded.Data[fmt.Sprintf("k%d", n-1)], int64(n-1))
+		}
+	}
+}
+
 func BenchmarkEventEncode(b *testing.B) {
 	tm, err := time.Parse(time.RFC3339, "2001-02-03T04:05:06Z")
 	testOK(b, err)
@@ -98,3 +338,41 @@ func BenchmarkEventEncode(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkBatchEncode(b *testing.B) {
+	tm, err := time.Parse(time.RFC3339, "2001-02-03T04:05:06Z")
+	testOK(b, err)
+	events := make([]*Event, 50)
+	for i := range events {
+		events[i] = &Event{
+			SampleRate: 2,
+			Timestamp:  tm,
+			Data: map[string]interface{}{
+				"a": int64(i),
+				"b": float64(1.5),
+				"c": true,
+				"d": "foo",
+				"e": tm,
+				"f": []byte("bar"),
+			},
+		}
+	}
+	batch := make([]*Event, len(events))
+	agg := &batchAgg{counters: &counters{}}
+
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			copy(batch, events)
+			agg.encodeBatch(NewJSONBatchEncoder(KeyPolicyEscape), batch)
+		}
+	})
+
+	b.Run("msgpack", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			copy(batch, events)
+			agg.encodeBatch(NewMsgpackBatchEncoder(KeyPolicyEscape), batch)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package transmission
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// KeyPolicy controls what a Honeycomb sender does with field names that aren't
+// valid UTF-8 or that contain quotes, backslashes or control characters.
+type KeyPolicy int
+
+const (
+	// KeyPolicyEscape sends such names escaped as the encoding requires, so
+	// that they arrive unchanged (except that invalid UTF-8 becomes U+FFFD in
+	// JSON). This is the default.
+	KeyPolicyEscape KeyPolicy = iota
+
+	// KeyPolicySanitize replaces each offending character with an underscore.
+	// A field whose sanitized name is already in use by another field is left
+	// out.
+	KeyPolicySanitize
+
+	// KeyPolicyReject drops events with such names, sending a Response whose
+	// Err is an *InvalidKeyError.
+	KeyPolicyReject
+)
+
+// InvalidKeyError is the Response error for an event dropped under
+// KeyPolicyReject.
+type InvalidKeyError struct {
+	Key string
+}
+
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("invalid field name %q", e.Key)
+}
+
+// keySafe reports whether c can appear in a field name without attention.
+func keySafe(c byte) bool {
+	return c >= 0x20 && c != 0x7f && c != '"' && c != '\\'
+}
+
+// validKey reports whether k is valid UTF-8 free of quotes, backslashes and
+// control characters.
+func validKey(k string) bool {
+	for i := 0; i < len(k); {
+		if c := k[i]; c < utf8.RuneSelf {
+			if !keySafe(c) {
+				return false
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(k[i:])
+		if r == utf8.RuneError && size == 1 {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+// sanitizeKey replaces each byte of k that validKey objects to with "_".
+func sanitizeKey(k string) string {
+	var b strings.Builder
+	b.Grow(len(k))
+	for i := 0; i < len(k); {
+		if c := k[i]; c < utf8.RuneSelf {
+			if keySafe(c) {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('_')
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(k[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteByte('_')
+		} else {
+			b.WriteString(k[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// keyFilter applies a KeyPolicy to the field names of one event at a time.
+type keyFilter struct {
+	policy KeyPolicy
+	// sanitized holds the names produced by sanitizing, so that two invalid
+	// names can't produce the same field twice
+	sanitized map[string]bool
+}
+
+// reset prepares f for the next event.
+func (f *keyFilter) reset() {
+	for k := range f.sanitized {
+		delete(f.sanitized, k)
+	}
+}
+
+// apply returns the name to send for the field k of data, or ok false if the
+// field should be left out. It returns an *InvalidKeyError if the whole event
+// should be dropped.
+func (f *keyFilter) apply(k string, data map[string]interface{}) (key string, ok bool, err error) {
+	if f.policy == KeyPolicyEscape || validKey(k) {
+		return k, true, nil
+	}
+	if f.policy == KeyPolicyReject {
+		return "", false, &InvalidKeyError{Key: k}
+	}
+	key = sanitizeKey(k)
+	if _, exists := data[key]; exists || f.sanitized[key] {
+		return "", false, nil
+	}
+	if f.sanitized == nil {
+		f.sanitized = make(map[string]bool)
+	}
+	f.sanitized[key] = true
+	return key, true, nil
+}
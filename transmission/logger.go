@@ -1,10 +1,20 @@
 package transmission
 
+import "github.com/honeycombio/libhoney-go/internal/logging"
+
 type Logger interface {
 	// Printf accepts the same msg, args style as fmt.Printf().
 	Printf(msg string, args ...interface{})
 }
 
+// LeveledLogger is an optional extension of Logger. If the Logger given to a
+// Sender also has Debug, Info, Warn and Error methods, each taking a message
+// and alternating key/value pairs, messages are logged with the one matching
+// their severity instead of through Printf.
+type LeveledLogger interface {
+	logging.LeveledLogger
+}
+
 type nullLogger struct{}
 
 // Printf swallows messages
@@ -7,8 +7,79 @@ type Metrics interface {
 	Count(string, interface{})
 }
 
+// DistributionMetrics is an optional extension of Metrics for implementations
+// that can record distributions of values, such as statsd timers and
+// histograms. If the Metrics given to the Honeycomb sender also implements
+// DistributionMetrics, it will additionally receive per-batch request
+// durations (in milliseconds), event counts, encoded and compressed sizes and
+// compression ratios. The statsd client used by libhoney by default satisfies
+// this interface.
+type DistributionMetrics interface {
+	Metrics
+	Timing(string, interface{})
+	Histogram(string, interface{})
+}
+
+// The names of the counters and gauges reported through Metrics by the
+// Honeycomb sender.
+var (
+	counterNames = []string{
+		"messages_queued",
+		"messages_sent",
+		"queue_overflow",
+		"send_errors",
+		"batches_sent",
+		"send_retries",
+		"response_decode_errors",
+		"compression_time_us",
+	}
+	gaugeNames = []string{
+		"queue_length",
+		"compression_ratio",
+	}
+	distributionNames = []string{
+		"batch_request_duration_ms",
+		"batch_events",
+		"batch_bytes_encoded",
+		"batch_bytes_compressed",
+		"batch_compression_ratio",
+	}
+)
+
 type nullMetrics struct{}
 
 func (nm *nullMetrics) Gauge(string, interface{}) {}
 func (nm *nullMetrics) Increment(string)          {}
 func (nm *nullMetrics) Count(string, interface{}) {}
+
+// metricValue converts the value handed to Gauge or Count to a float64. It
+// returns false for non-numeric values, which are ignored.
+func metricValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,76 @@
+package transmission
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// ExpvarMetrics implements Metrics by publishing counters and gauges in an
+// expvar.Map, making them available at /debug/vars alongside the rest of the
+// process's expvars. Create one with NewExpvarMetrics.
+type ExpvarMetrics struct {
+	vars *expvar.Map
+
+	// gaugeLock guards creating a gauge's expvar.Float on first use
+	gaugeLock sync.Mutex
+}
+
+// expvarLock serializes looking up and publishing maps, so that concurrent
+// calls to NewExpvarMetrics can't both try to publish the same name.
+var expvarLock sync.Mutex
+
+// NewExpvarMetrics returns an ExpvarMetrics publishing under name (e.g.
+// "libhoney"). Calling it more than once with the same name shares the same
+// underlying map, since expvar names can only be published once per process.
+// It returns an error if name is already published as something other than
+// an expvar.Map.
+func NewExpvarMetrics(name string) (*ExpvarMetrics, error) {
+	expvarLock.Lock()
+	var vars *expvar.Map
+	switch v := expvar.Get(name).(type) {
+	case nil:
+		vars = expvar.NewMap(name)
+	case *expvar.Map:
+		vars = v
+	default:
+		expvarLock.Unlock()
+		return nil, fmt.Errorf("expvar %q is already published as a %T", name, v)
+	}
+	expvarLock.Unlock()
+	m := &ExpvarMetrics{vars: vars}
+	for _, n := range counterNames {
+		if vars.Get(n) == nil {
+			vars.Add(n, 0)
+		}
+	}
+	for _, n := range gaugeNames {
+		m.Gauge(n, 0)
+	}
+	return m, nil
+}
+
+func (m *ExpvarMetrics) Gauge(name string, val interface{}) {
+	f, ok := metricValue(val)
+	if !ok {
+		return
+	}
+	m.gaugeLock.Lock()
+	defer m.gaugeLock.Unlock()
+	g, ok := m.vars.Get(name).(*expvar.Float)
+	if !ok {
+		g = new(expvar.Float)
+		m.vars.Set(name, g)
+	}
+	g.Set(f)
+}
+
+func (m *ExpvarMetrics) Increment(name string) {
+	m.vars.Add(name, 1)
+}
+
+func (m *ExpvarMetrics) Count(name string, val interface{}) {
+	if f, ok := metricValue(val); ok {
+		m.vars.Add(name, int64(f))
+	}
+}
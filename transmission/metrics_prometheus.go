@@ -0,0 +1,147 @@
+package transmission
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PrometheusMetrics implements Metrics by keeping counters and gauges in
+// memory, and http.Handler by serving them in the Prometheus text exposition
+// format. Mount it on your metrics endpoint, e.g.
+//
+//	http.Handle("/metrics", transmission.NewPrometheusMetrics("libhoney"))
+//
+// Counters are exposed with a _total suffix, per Prometheus convention.
+// Timings and histograms are exposed as summaries without quantiles, i.e. a
+// _sum and a _count.
+type PrometheusMetrics struct {
+	namespace string
+
+	counters  map[string]float64
+	gauges    map[string]float64
+	summaries map[string]*summary
+	lock      sync.Mutex
+}
+
+type summary struct {
+	sum   float64
+	count uint64
+}
+
+// NewPrometheusMetrics returns a PrometheusMetrics whose metric names are
+// prefixed with namespace and an underscore. The counters and gauges reported
+// by the Honeycomb sender are present (at zero) from the start.
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		namespace: namespace,
+		counters:  map[string]float64{},
+		gauges:    map[string]float64{},
+		summaries: map[string]*summary{},
+	}
+	for _, n := range counterNames {
+		m.counters[n] = 0
+	}
+	for _, n := range gaugeNames {
+		m.gauges[n] = 0
+	}
+	for _, n := range distributionNames {
+		m.summaries[n] = &summary{}
+	}
+	return m
+}
+
+func (m *PrometheusMetrics) Gauge(name string, val interface{}) {
+	if f, ok := metricValue(val); ok {
+		m.lock.Lock()
+		m.gauges[name] = f
+		m.lock.Unlock()
+	}
+}
+
+func (m *PrometheusMetrics) Increment(name string) {
+	m.lock.Lock()
+	m.counters[name]++
+	m.lock.Unlock()
+}
+
+func (m *PrometheusMetrics) Count(name string, val interface{}) {
+	if f, ok := metricValue(val); ok {
+		m.lock.Lock()
+		m.counters[name] += f
+		m.lock.Unlock()
+	}
+}
+
+func (m *PrometheusMetrics) Timing(name string, val interface{}) {
+	m.Histogram(name, val)
+}
+
+func (m *PrometheusMetrics) Histogram(name string, val interface{}) {
+	if f, ok := metricValue(val); ok {
+		m.lock.Lock()
+		s, ok := m.summaries[name]
+		if !ok {
+			s = &summary{}
+			m.summaries[name] = s
+		}
+		s.sum += f
+		s.count++
+		m.lock.Unlock()
+	}
+}
+
+// ServeHTTP writes all counters and gauges in the Prometheus text format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	buf := bufio.NewWriter(w)
+	defer buf.Flush()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, name := range sortedKeys(m.counters) {
+		fullName := m.metricName(name) + "_total"
+		fmt.Fprintf(buf, "# TYPE %s counter\n%s %v\n", fullName, fullName, m.counters[name])
+	}
+	for _, name := range sortedKeys(m.gauges) {
+		fullName := m.metricName(name)
+		fmt.Fprintf(buf, "# TYPE %s gauge\n%s %v\n", fullName, fullName, m.gauges[name])
+	}
+	names := make([]string, 0, len(m.summaries))
+	for name := range m.summaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fullName := m.metricName(name)
+		s := m.summaries[name]
+		fmt.Fprintf(buf, "# TYPE %s summary\n%s_sum %v\n%s_count %d\n", fullName, fullName, s.sum, fullName, s.count)
+	}
+}
+
+// metricName prefixes name with the namespace and replaces any characters
+// Prometheus doesn't allow in metric names with underscores.
+func (m *PrometheusMetrics) metricName(name string) string {
+	if m.namespace != "" {
+		name = m.namespace + "_" + name
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,69 @@
+package transmission
+
+import (
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics("libhoney")
+	m.Increment("send_errors")
+	m.Increment("send_errors")
+	m.Count("messages_sent", 12)
+	m.Gauge("queue_length", 3)
+	m.Gauge("ignored", "not a number")
+	m.Histogram("batch_events", 10)
+	m.Histogram("batch_events", 20)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	for _, line := range []string{
+		"# TYPE libhoney_send_errors_total counter",
+		"libhoney_send_errors_total 2",
+		"libhoney_messages_sent_total 12",
+		"libhoney_batches_sent_total 0",
+		"# TYPE libhoney_queue_length gauge",
+		"libhoney_queue_length 3",
+		"# TYPE libhoney_batch_events summary",
+		"libhoney_batch_events_sum 30",
+		"libhoney_batch_events_count 2",
+		"libhoney_batch_request_duration_ms_count 0",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected output to contain %q, got:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "ignored") {
+		t.Errorf("non-numeric gauge should not be exported, got:\n%s", body)
+	}
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	m, err := NewExpvarMetrics("libhoney_test")
+	testOK(t, err)
+	m.Increment("send_retries")
+	m.Count("messages_sent", 5)
+	m.Gauge("queue_length", 7)
+
+	vars := expvar.Get("libhoney_test").(*expvar.Map)
+	testEquals(t, vars.Get("send_retries").String(), "1")
+	testEquals(t, vars.Get("messages_sent").String(), "5")
+	testEquals(t, vars.Get("queue_length").String(), "7")
+	testEquals(t, vars.Get("response_decode_errors").String(), "0")
+	testEquals(t, vars.Get("compression_ratio").String(), "0")
+
+	// registering the same name again must not panic and shares the values
+	m2, err := NewExpvarMetrics("libhoney_test")
+	testOK(t, err)
+	m2.Increment("send_retries")
+	testEquals(t, vars.Get("send_retries").String(), "2")
+
+	// a name published as something else is an error rather than a panic
+	expvar.NewInt("libhoney_test_int")
+	_, err = NewExpvarMetrics("libhoney_test_int")
+	testErr(t, err)
+}
@@ -0,0 +1,125 @@
+package transmission
+
+import (
+	"runtime"
+	"sync"
+)
+
+// DefaultMaxInFlightBytes is the default for Honeycomb.MaxInFlightBytes.
+const DefaultMaxInFlightBytes = 100 * 1000 * 1000 // 100MB
+
+// pipeline bounds the work a Honeycomb sender does across all of its
+// concurrent flushes. Each batch is encoded and compressed by one of a fixed
+// number of workers, which keeps it until there is room for its body under
+// the in-flight bytes cap. The batch then waits for a send slot for its
+// destination before its request is made. A slow destination holds only its
+// own send slots, so batches for other datasets carry on past it until the
+// bytes waiting for it fill the cap.
+type pipeline struct {
+	encodeSlots chan struct{}
+
+	perDestination int
+	destLock       sync.Mutex
+	// destSlots only holds destinations with a batch waiting or in flight,
+	// so that it doesn't grow with every dataset ever sent to
+	destSlots map[string]*destSlots
+
+	bytes byteBudget
+}
+
+func newPipeline(encodeWorkers, perDestination, maxInFlightBytes uint) *pipeline {
+	if encodeWorkers == 0 {
+		encodeWorkers = uint(runtime.GOMAXPROCS(0))
+	}
+	p := &pipeline{
+		encodeSlots:    make(chan struct{}, encodeWorkers),
+		perDestination: int(perDestination),
+		destSlots:      map[string]*destSlots{},
+	}
+	p.bytes.max = int(maxInFlightBytes)
+	p.bytes.cond.L = &p.bytes.lock
+	return p
+}
+
+// encode runs f, which encodes a batch and returns the size of its body, in an
+// encode slot. The slot is kept until that many bytes fit under the in-flight
+// cap, so only the encode workers ever hold a body the cap doesn't count. It
+// returns the function that gives the bytes back.
+//
+// Waiting for bytes while holding an encode slot can't deadlock: a batch
+// holding bytes waits only for a destination slot, and those are held by
+// batches whose requests are being made.
+func (p *pipeline) encode(f func() int) func() {
+	p.encodeSlots <- struct{}{}
+	defer func() { <-p.encodeSlots }()
+	n := f()
+	p.bytes.acquire(n)
+	return func() { p.bytes.release(n) }
+}
+
+// acquireSend waits until a request may be sent to dest, and returns the
+// function that releases its place.
+func (p *pipeline) acquireSend(dest string) func() {
+	if p.perDestination <= 0 {
+		return func() {}
+	}
+	p.destLock.Lock()
+	slots := p.destSlots[dest]
+	if slots == nil {
+		slots = &destSlots{slots: make(chan struct{}, p.perDestination)}
+		p.destSlots[dest] = slots
+	}
+	slots.users++
+	p.destLock.Unlock()
+	slots.slots <- struct{}{}
+	return func() {
+		<-slots.slots
+		p.destLock.Lock()
+		slots.users--
+		if slots.users == 0 {
+			delete(p.destSlots, dest)
+		}
+		p.destLock.Unlock()
+	}
+}
+
+// destSlots are the send slots for one destination.
+type destSlots struct {
+	slots chan struct{}
+	// users counts the batches waiting for or holding a slot; guarded by
+	// pipeline.destLock
+	users int
+}
+
+// byteBudget is a counting semaphore over bytes. A zero max means no limit.
+type byteBudget struct {
+	max  int
+	used int
+	lock sync.Mutex
+	cond sync.Cond
+}
+
+// acquire waits until n more bytes fit under the limit. A request larger than
+// the whole limit is let through once nothing else is in flight, so that it
+// can't wait forever.
+func (b *byteBudget) acquire(n int) {
+	if b.max <= 0 || n == 0 {
+		return
+	}
+	b.lock.Lock()
+	for b.used > 0 && b.used+n > b.max {
+		b.cond.Wait()
+	}
+	b.used += n
+	b.lock.Unlock()
+}
+
+func (b *byteBudget) release(n int) {
+	if b.max <= 0 {
+		return
+	}
+	b.lock.Lock()
+	b.used -= n
+	b.lock.Unlock()
+	b.cond.Broadcast()
+}
@@ -0,0 +1,164 @@
+package transmission
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestByteBudget(t *testing.T) {
+	p := newPipeline(1, 0, 100)
+	free := p.encode(func() int { return 60 })
+
+	encoding := make(chan struct{})
+	acquired := make(chan struct{})
+	go func() {
+		defer close(acquired)
+		p.encode(func() int {
+			close(encoding)
+			return 60
+		})()
+	}()
+	<-encoding
+	// the batch waiting for bytes keeps the only encode slot
+	encoded := make(chan struct{})
+	go func() {
+		p.encode(func() int {
+			close(encoded)
+			return 0
+		})()
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired bytes over the cap")
+	case <-encoded:
+		t.Fatal("encoded a batch while another waited for bytes")
+	case <-time.After(50 * time.Millisecond):
+	}
+	free()
+	<-acquired
+	<-encoded
+
+	// a batch larger than the cap goes through on its own
+	p.encode(func() int { return 1000 })()
+}
+
+func TestDestinationSlotsAreDropped(t *testing.T) {
+	p := newPipeline(1, 1, 0)
+	release := p.acquireSend("a")
+
+	acquired := make(chan struct{})
+	go func() {
+		defer close(acquired)
+		p.acquireSend("a")()
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired more than one slot for a destination")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	<-acquired
+
+	// once nothing is waiting for or sending to a destination its slots go
+	for i := 0; i < 100; i++ {
+		p.acquireSend(fmt.Sprintf("dataset%d", i))()
+	}
+	p.destLock.Lock()
+	testEquals(t, len(p.destSlots), 0)
+	p.destLock.Unlock()
+}
+
+// blockingRoundTripper accepts every event, but holds requests for the
+// "slow" dataset until unblock is closed. It records the most requests seen
+// in flight at once per dataset.
+type blockingRoundTripper struct {
+	allResponsesRoundTripper
+	unblock chan struct{}
+
+	lock        sync.Mutex
+	inFlight    map[string]int
+	maxInFlight map[string]int
+}
+
+func (b *blockingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	dataset := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	b.lock.Lock()
+	b.inFlight[dataset]++
+	if b.inFlight[dataset] > b.maxInFlight[dataset] {
+		b.maxInFlight[dataset] = b.inFlight[dataset]
+	}
+	b.lock.Unlock()
+	defer func() {
+		b.lock.Lock()
+		b.inFlight[dataset]--
+		b.lock.Unlock()
+	}()
+
+	if dataset == "slow" {
+		<-b.unblock
+	} else {
+		// give concurrent requests a chance to overlap
+		time.Sleep(10 * time.Millisecond)
+	}
+	return b.allResponsesRoundTripper.RoundTrip(r)
+}
+
+func TestPipelineParallelDestinations(t *testing.T) {
+	rt := &blockingRoundTripper{
+		unblock:     make(chan struct{}),
+		inFlight:    map[string]int{},
+		maxInFlight: map[string]int{},
+	}
+	p := newPipeline(2, 1, 0)
+	responses := make(chan Response, 20)
+	newBatchAgg := func() *batchAgg {
+		return &batchAgg{
+			httpClient:         &http.Client{Transport: rt},
+			responses:          responses,
+			metrics:            &nullMetrics{},
+			disableCompression: true,
+			pipeline:           p,
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		b := newBatchAgg()
+		for _, dataset := range []string{"slow", "fast"} {
+			b.Add(&Event{
+				Data:     map[string]interface{}{"a": i},
+				APIHost:  "http://fakeHost:8080",
+				APIKey:   "written",
+				Dataset:  dataset,
+				Metadata: dataset,
+			})
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Fire(&testNotifier{})
+		}()
+	}
+
+	// every fast batch gets through while the slow destination is stuck
+	for i := 0; i < 4; i++ {
+		select {
+		case resp := <-responses:
+			testEquals(t, resp.Metadata, "fast")
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the fast destination")
+		}
+	}
+	close(rt.unblock)
+	wg.Wait()
+	testEquals(t, len(responses), 4)
+
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	testEquals(t, rt.maxInFlight["slow"], 1)
+	testEquals(t, rt.maxInFlight["fast"], 1)
+}
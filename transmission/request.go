@@ -0,0 +1,42 @@
+package transmission
+
+import (
+	"context"
+	"net/http"
+)
+
+// BatchInfo describes the batch carried by a request, for a RequestModifier.
+type BatchInfo struct {
+	// Dataset is the dataset the batch is sent to.
+	Dataset string
+	// Events is the number of events encoded in the batch.
+	Events int
+}
+
+type batchInfoKey struct{}
+
+// RequestBatchInfo returns the BatchInfo for a batch request made by the
+// Honeycomb sender. It returns false for other requests, such as verifying the
+// API key.
+func RequestBatchInfo(req *http.Request) (BatchInfo, bool) {
+	info, ok := req.Context().Value(batchInfoKey{}).(BatchInfo)
+	return info, ok
+}
+
+// ModifyRequest applies Headers and then RequestModifier to req. The sender
+// calls it for each batch request just before sending it; it is exported so
+// that other requests to Honeycomb can be treated the same way.
+func (h *Honeycomb) ModifyRequest(req *http.Request) error {
+	for k, v := range h.Headers {
+		req.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	if h.RequestModifier != nil {
+		return h.RequestModifier(req)
+	}
+	return nil
+}
+
+// withBatchInfo attaches info to req for RequestBatchInfo.
+func withBatchInfo(req *http.Request, info BatchInfo) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), batchInfoKey{}, info))
+}
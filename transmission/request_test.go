@@ -0,0 +1,94 @@
+package transmission
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestModifier(t *testing.T) {
+	var info BatchInfo
+	var signed string
+	h := &Honeycomb{
+		Headers: http.Header{
+			"x-proxy-token": []string{"secret"},
+			"User-Agent":    []string{"overridden"},
+		},
+		RequestModifier: func(req *http.Request) error {
+			var ok bool
+			info, ok = RequestBatchInfo(req)
+			testEquals(t, ok, true)
+			testEquals(t, req.Header.Get("X-Proxy-Token"), "secret")
+			body, err := req.GetBody()
+			testOK(t, err)
+			defer body.Close()
+			b, err := ioutil.ReadAll(body)
+			testOK(t, err)
+			sum := sha256.Sum256(b)
+			signed = hex.EncodeToString(sum[:])
+			req.Header.Set("X-Signature", signed)
+			return nil
+		},
+	}
+	frt := &FakeRoundTripper{
+		resp: &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202},{"status":202}]`)),
+		},
+	}
+	b := &batchAgg{
+		httpClient:    &http.Client{Transport: frt},
+		testNower:     &fakeNower{},
+		responses:     make(chan Response, 2),
+		metrics:       &nullMetrics{},
+		modifyRequest: h.ModifyRequest,
+	}
+	b.fireBatch([]*Event{
+		{Data: map[string]interface{}{"a": 1}, APIHost: "http://fakeHost:8080", APIKey: "written", Dataset: "ds1"},
+		{Data: map[string]interface{}{"a": 2}, APIHost: "http://fakeHost:8080", APIKey: "written", Dataset: "ds1"},
+	})
+
+	testEquals(t, info, BatchInfo{Dataset: "ds1", Events: 2})
+	testEquals(t, frt.req.Header.Get("X-Proxy-Token"), "secret")
+	testEquals(t, frt.req.Header.Get("User-Agent"), "overridden")
+	testEquals(t, frt.req.Header.Get("X-Honeycomb-Team"), "written")
+	// reading the body to sign it left the request's own copy intact
+	sum := sha256.Sum256([]byte(frt.reqBody))
+	testEquals(t, frt.req.Header.Get("X-Signature"), hex.EncodeToString(sum[:]))
+	testEquals(t, signed, hex.EncodeToString(sum[:]))
+	for i := 0; i < 2; i++ {
+		testEquals(t, (<-b.responses).Err, nil)
+	}
+
+	// an error from the modifier fails the batch without sending it
+	signErr := errors.New("no signing key")
+	h.RequestModifier = func(*http.Request) error { return signErr }
+	frt.req = nil
+	b.fireBatch([]*Event{
+		{Data: map[string]interface{}{"a": 1}, APIHost: "http://fakeHost:8080", APIKey: "written", Dataset: "ds1"},
+	})
+	testEquals(t, frt.req == nil, true)
+	testEquals(t, (<-b.responses).Err, signErr)
+
+	// requests that aren't batches carry no BatchInfo
+	req, err := http.NewRequest("GET", "http://fakeHost:8080/1/auth", nil)
+	testOK(t, err)
+	_, ok := RequestBatchInfo(req)
+	testEquals(t, ok, false)
+}
+
+func TestRequestModifierOnlyWhenConfigured(t *testing.T) {
+	h := &Honeycomb{MaxBatchSize: 1, PendingWorkCapacity: 1}
+	testOK(t, h.Start())
+	testEquals(t, h.muster.BatchMaker().(*batchAgg).modifyRequest == nil, true)
+	testOK(t, h.Stop())
+
+	h = &Honeycomb{MaxBatchSize: 1, PendingWorkCapacity: 1, Headers: http.Header{"X-A": []string{"b"}}}
+	testOK(t, h.Start())
+	testEquals(t, h.muster.BatchMaker().(*batchAgg).modifyRequest != nil, true)
+	testOK(t, h.Stop())
+}
@@ -0,0 +1,118 @@
+package transmission
+
+import "sync/atomic"
+
+// Reasons an event may be dropped without being sent, used as the keys of
+// Stats.Dropped.
+const (
+	DropReasonQueueOverflow = "queue_overflow"
+	DropReasonTooLarge      = "event_too_large"
+	DropReasonEncodeError   = "encode_error"
+	DropReasonInvalidKey    = "invalid_key"
+)
+
+// Stats is a point-in-time snapshot of a Honeycomb sender's counters. It
+// carries the same information that is reported through the Metrics
+// interface, for callers that want to read it in-process.
+type Stats struct {
+	// QueueLength is the number of events waiting to be batched.
+	QueueLength int
+
+	// Queued is the number of events accepted onto the queue.
+	Queued int64
+
+	// Sent is the number of events in batches that got an HTTP response from
+	// the API. Check SendErrors and the Responses channel for rejections.
+	Sent int64
+
+	// Dropped counts events that were never sent to Honeycomb, keyed by
+	// reason.
+	Dropped map[string]int64
+
+	// SendErrors is the number of batches that failed to send, either because
+	// the request failed or because the API returned a non-200 status.
+	SendErrors int64
+
+	// Retries is the number of times a batch was retried after a timeout.
+	Retries int64
+
+	// BatchesSent is the number of batches that got an HTTP response from the
+	// API.
+	BatchesSent int64
+
+	// BytesSent is the number of request body bytes (after compression) in
+	// batches that got an HTTP response from the API.
+	BytesSent int64
+
+	// ResponseDecodeErrors is the number of batch responses that couldn't be
+	// decoded.
+	ResponseDecodeErrors int64
+}
+
+// counter identifies one of the running totals kept by counters.
+type counter int
+
+const (
+	counterQueued counter = iota
+	counterSent
+	counterQueueOverflow
+	counterTooLarge
+	counterEncodeErrors
+	counterInvalidKeys
+	counterSendErrors
+	counterRetries
+	counterBatchesSent
+	counterBytesSent
+	counterResponseDecodeErrors
+	numCounters
+)
+
+// dropReasons maps each Stats.Dropped key to the counter behind it.
+var dropReasons = map[string]counter{
+	DropReasonQueueOverflow: counterQueueOverflow,
+	DropReasonTooLarge:      counterTooLarge,
+	DropReasonEncodeError:   counterEncodeErrors,
+	DropReasonInvalidKey:    counterInvalidKeys,
+}
+
+// counters holds the running totals behind Stats. All values are updated
+// atomically; a nil *counters discards updates so that batchAggs built
+// directly (as in tests) don't need one.
+type counters struct {
+	vals [numCounters]int64
+}
+
+func (c *counters) add(which counter, n int64) {
+	if c == nil {
+		return
+	}
+	atomic.AddInt64(&c.vals[which], n)
+}
+
+func (c *counters) dropped(reason string) {
+	if which, ok := dropReasons[reason]; ok {
+		c.add(which, 1)
+	}
+}
+
+func (c *counters) get(which counter) int64 {
+	return atomic.LoadInt64(&c.vals[which])
+}
+
+func (c *counters) snapshot() Stats {
+	s := Stats{Dropped: map[string]int64{}}
+	if c == nil {
+		return s
+	}
+	s.Queued = c.get(counterQueued)
+	s.Sent = c.get(counterSent)
+	for reason, which := range dropReasons {
+		s.Dropped[reason] = c.get(which)
+	}
+	s.SendErrors = c.get(counterSendErrors)
+	s.Retries = c.get(counterRetries)
+	s.BatchesSent = c.get(counterBatchesSent)
+	s.BytesSent = c.get(counterBytesSent)
+	s.ResponseDecodeErrors = c.get(counterResponseDecodeErrors)
+	return s
+}
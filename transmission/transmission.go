@@ -11,7 +11,7 @@ package transmission
 // Ensure Stop() is called to flush all in-flight messages.
 
 import (
-	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,7 +25,7 @@ import (
 	"time"
 
 	"github.com/facebookgo/muster"
-	"github.com/klauspost/compress/zstd"
+	"github.com/honeycombio/libhoney-go/internal/logging"
 	"github.com/vmihailenco/msgpack/v4"
 )
 
@@ -65,15 +65,106 @@ type Honeycomb struct {
 	// Deprecated, synonymous with DisableCompression
 	DisableGzipCompression bool
 
-	// set true to send events with msgpack encoding
+	// which codec compresses batches; defaults to zstd
+	Compression Compression
+
+	// the codec's compression level: 1-22 for zstd, 1-9 for gzip. 0 picks a
+	// default that balances speed against size.
+	CompressionLevel int
+
+	// batches whose encoded size is under this many bytes are sent
+	// uncompressed, since compressing tiny batches costs more CPU than it
+	// saves; 0 compresses every batch
+	CompressionMinBytes uint
+
+	// set true to send events with msgpack encoding. Unlike JSON, which
+	// leaves out nil fields and fields it can't encode, msgpack sends nil
+	// fields as nil and fails an event with a field it can't encode.
 	EnableMsgpackEncoding bool
 
+	// what to do with field names that need escaping; see KeyPolicy
+	KeyPolicy KeyPolicy
+
+	// BatchEncoder, if set, encodes batches in place of the built-in JSON or
+	// msgpack encoders, and EnableMsgpackEncoding and KeyPolicy are ignored
+	BatchEncoder BatchEncoder
+
+	// how many batches can be encoded and compressed at once, across all
+	// inflight batches; defaults to GOMAXPROCS
+	EncodeWorkers uint
+
+	// how many batches can be inflight simultaneously to any one destination
+	// (API host, write key and dataset); 0 means no limit beyond
+	// MaxConcurrentBatches
+	MaxConcurrentBatchesPerDestination uint
+
+	// how many request body bytes can be held at once by batches waiting to
+	// be sent or being sent; defaults to DefaultMaxInFlightBytes. Only the
+	// (compressed) body of a batch is kept once it is encoded, and an encode
+	// worker isn't freed until its batch's body fits under this cap, so at
+	// most EncodeWorkers batches are held outside it. A batch larger than
+	// this is still sent, on its own.
+	MaxInFlightBytes uint
+
 	responses chan Response
 
+	// Transport, if set, makes the sender's requests, and the connection
+	// options below are ignored. Otherwise the sender builds a transport of
+	// its own, so its connection pool isn't shared with http.DefaultTransport.
 	Transport http.RoundTripper
 
+	// how long to wait for each batch request; defaults to
+	// DefaultRequestTimeout, and a negative value means no timeout
+	Timeout time.Duration
+
+	// how many idle connections to keep to each host; defaults to
+	// DefaultMaxIdleConnsPerHost
+	MaxIdleConnsPerHost int
+
+	// how long idle connections are kept; defaults to DefaultIdleConnTimeout
+	IdleConnTimeout time.Duration
+
+	// the TCP keep-alive period for connections; defaults to
+	// DefaultKeepAlive, and a negative value turns keep-alive probes off
+	KeepAlive time.Duration
+
+	// TLS settings for connections, such as custom CAs or a client
+	// certificate; see NewTLSConfig
+	TLSConfig *tls.Config
+
+	// chooses the proxy for each request, as http.Transport.Proxy does;
+	// defaults to http.ProxyFromEnvironment
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// set true to use HTTP/1.1 even when the server supports HTTP/2
+	DisableHTTP2 bool
+
+	// extra headers sent with every request, replacing any the sender sets
+	// with the same name
+	Headers http.Header
+
+	// RequestModifier, if set, is called with every request after its body
+	// and headers are in place, just before it is sent; it can add headers or
+	// sign the request, for example for an egress proxy. Use
+	// RequestBatchInfo to find the dataset and event count, and req.GetBody
+	// to read the body. An error fails the batch without sending it.
+	RequestModifier func(*http.Request) error
+
+	// the sender's own transport, when Transport isn't set, and the client
+	// shared by every batch; both survive restarts from Flush
+	transportOnce sync.Once
+	transport     http.RoundTripper
+	httpClient    *http.Client
+
 	muster muster.Client
 
+	// counters backs Stats; it is created on the first Start and survives
+	// restarts from Flush
+	counters *counters
+
+	pipeline   *pipeline
+	compressor compressor
+
 	Logger  Logger
 	Metrics Metrics
 }
@@ -82,7 +173,7 @@ func (h *Honeycomb) Start() error {
 	if h.Logger == nil {
 		h.Logger = &nullLogger{}
 	}
-	h.Logger.Printf("default transmission starting")
+	logging.Info(h.Logger, "default transmission starting")
 	h.responses = make(chan Response, h.PendingWorkCapacity*2)
 	h.muster.MaxBatchSize = h.MaxBatchSize
 	h.muster.BatchTimeout = h.BatchTimeout
@@ -91,54 +182,99 @@ func (h *Honeycomb) Start() error {
 	if h.Metrics == nil {
 		h.Metrics = &nullMetrics{}
 	}
+	if h.counters == nil {
+		h.counters = &counters{}
+	}
+	maxInFlightBytes := h.MaxInFlightBytes
+	if maxInFlightBytes == 0 {
+		maxInFlightBytes = DefaultMaxInFlightBytes
+	}
+	h.pipeline = newPipeline(h.EncodeWorkers, h.MaxConcurrentBatchesPerDestination, maxInFlightBytes)
+	// the compressor is expensive to create, so it survives restarts from
+	// Flush like the counters
+	if h.compressor == nil && h.Compression != CompressionNone {
+		c, err := newCompressor(h.Compression, h.CompressionLevel)
+		if err != nil {
+			return err
+		}
+		h.compressor = c
+	}
+	if h.httpClient == nil {
+		h.httpClient = h.newHTTPClient()
+	}
+	var modifyRequest func(*http.Request) error
+	if len(h.Headers) > 0 || h.RequestModifier != nil {
+		modifyRequest = h.ModifyRequest
+	}
 	h.muster.BatchMaker = func() muster.Batch {
 		return &batchAgg{
-			userAgentAddition: h.UserAgentAddition,
-			batches:           map[string][]*Event{},
-			httpClient: &http.Client{
-				Transport: h.Transport,
-				Timeout:   60 * time.Second,
-			},
+			userAgentAddition:     h.UserAgentAddition,
+			batches:               map[string][]*Event{},
+			httpClient:            h.httpClient,
 			blockOnResponse:       h.BlockOnResponse,
 			responses:             h.responses,
 			metrics:               h.Metrics,
-			disableCompression:    h.DisableGzipCompression || h.DisableCompression,
+			counters:              h.counters,
+			disableCompression:    h.DisableGzipCompression || h.DisableCompression || h.Compression == CompressionNone,
+			compressor:            h.compressor,
+			compressMinBytes:      int(h.CompressionMinBytes),
 			enableMsgpackEncoding: h.EnableMsgpackEncoding,
+			keyPolicy:             h.KeyPolicy,
+			batchEncoder:          h.BatchEncoder,
+			modifyRequest:         modifyRequest,
+			pipeline:              h.pipeline,
 		}
 	}
 	return h.muster.Start()
 }
 
 func (h *Honeycomb) Stop() error {
-	h.Logger.Printf("Honeycomb transmission stopping")
+	logging.Info(h.Logger, "Honeycomb transmission stopping")
 	err := h.muster.Stop()
 	close(h.responses)
+	// a Transport passed in belongs to the caller, but the sender's own has
+	// no other users to clean up after it
+	if h.Transport == nil {
+		if t, ok := h.RoundTripper().(*http.Transport); ok {
+			t.CloseIdleConnections()
+		}
+	}
 	return err
 }
 
 func (h *Honeycomb) Add(ev *Event) {
-	h.Logger.Printf("adding event to transmission; queue length %d", len(h.muster.Work))
+	logging.Debug(h.Logger, "adding event to transmission", "queue_length", len(h.muster.Work))
 	h.Metrics.Gauge("queue_length", len(h.muster.Work))
 	if h.BlockOnSend {
 		h.muster.Work <- ev
 		h.Metrics.Increment("messages_queued")
+		h.counters.add(counterQueued, 1)
 	} else {
 		select {
 		case h.muster.Work <- ev:
 			h.Metrics.Increment("messages_queued")
+			h.counters.add(counterQueued, 1)
 		default:
 			h.Metrics.Increment("queue_overflow")
+			h.counters.dropped(DropReasonQueueOverflow)
 			r := Response{
 				Err:      errors.New("queue overflow"),
 				Metadata: ev.Metadata,
 			}
-			h.Logger.Printf("got response code %d, error %s, and body %s",
-				r.StatusCode, r.Err, string(r.Body))
+			logging.Warn(h.Logger, "dropping event", "err", r.Err, "queue_length", len(h.muster.Work))
 			writeToResponse(h.responses, r, h.BlockOnResponse)
+			release(ev)
 		}
 	}
 }
 
+// Stats returns a snapshot of the sender's counters and current queue length.
+func (h *Honeycomb) Stats() Stats {
+	s := h.counters.snapshot()
+	s.QueueLength = len(h.muster.Work)
+	return s
+}
+
 func (h *Honeycomb) TxResponses() chan Response {
 	return h.responses
 }
@@ -163,16 +299,26 @@ type batchAgg struct {
 	batches map[string][]*Event
 	// Used to reenque events when an initial batch is too large
 	overflowBatches       map[string][]*Event
+	overflowLock          sync.Mutex
 	httpClient            *http.Client
 	blockOnResponse       bool
 	userAgentAddition     string
 	disableCompression    bool
+	compressor            compressor
+	compressMinBytes      int
 	enableMsgpackEncoding bool
+	keyPolicy             KeyPolicy
+	batchEncoder          BatchEncoder
+	modifyRequest         func(*http.Request) error
 
 	responses chan Response
 	// numEncoded       int
 
-	metrics Metrics
+	metrics  Metrics
+	counters *counters
+	// pipeline, if set, sends the batches for different destinations in
+	// parallel
+	pipeline *pipeline
 
 	// allows manipulation of the value of "now" for testing
 	testNower   nower
@@ -203,7 +349,26 @@ func (b *batchAgg) enqueueResponse(resp Response) {
 	}
 }
 
+// respond sends resp as the response to ev, then releases ev; the caller must
+// not use ev afterwards.
+func (b *batchAgg) respond(ev *Event, resp Response) {
+	resp.Metadata = ev.Metadata
+	b.enqueueResponse(resp)
+	release(ev)
+}
+
+// clearEvents nils out events that have been handed off to a later batch, so
+// that this batch doesn't also respond to them.
+func clearEvents(events []*Event) {
+	for i := range events {
+		events[i] = nil
+	}
+}
+
 func (b *batchAgg) reenqueueEvents(events []*Event) {
+	// batches for different datasets are encoded in parallel
+	b.overflowLock.Lock()
+	defer b.overflowLock.Unlock()
 	if b.overflowBatches == nil {
 		b.overflowBatches = make(map[string][]*Event)
 	}
@@ -218,9 +383,11 @@ func (b *batchAgg) Fire(notifier muster.Notifier) {
 
 	// send each batchKey's collection of event as a POST to /1/batch/<dataset>
 	// we don't need the batch key anymore; it's done its sorting job
+	batches := make([][]*Event, 0, len(b.batches))
 	for _, events := range b.batches {
-		b.fireBatch(events)
+		batches = append(batches, events)
 	}
+	b.fireBatches(batches)
 	// The initial batches could have had payloads that were greater than 5MB.
 	// The remaining events will have overflowed into overflowBatches
 	// Process these until complete. Overflow batches can also overflow, so we
@@ -234,69 +401,126 @@ func (b *batchAgg) Fire(notifier muster.Notifier) {
 				break
 			}
 			overflowCount++
-			// fetch the keys in this map - we can't range over the map
-			// because it's possible that fireBatch will reenqueue more overflow
-			// events
-			keys := make([]string, len(b.overflowBatches))
-			i := 0
-			for k := range b.overflowBatches {
-				keys[i] = k
-				i++
-			}
-
-			for _, k := range keys {
-				events := b.overflowBatches[k]
-				// fireBatch may append more overflow events
-				// so we want to clear this key before firing the batch
+			// take this round's batches out of the map, since firing them
+			// may reenqueue more overflow events
+			batches = batches[:0]
+			for k, events := range b.overflowBatches {
+				batches = append(batches, events)
 				delete(b.overflowBatches, k)
-				b.fireBatch(events)
 			}
+			b.fireBatches(batches)
 		}
 	}
 }
 
+// fireBatches sends each of batches. With a pipeline they go in parallel,
+// bounded by its limits; otherwise they are sent one after another.
+func (b *batchAgg) fireBatches(batches [][]*Event) {
+	if b.pipeline == nil {
+		for _, events := range batches {
+			b.fireBatch(events)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	for _, events := range batches {
+		wg.Add(1)
+		go func(events []*Event) {
+			defer wg.Done()
+			var pb *preparedBatch
+			freeBytes := b.pipeline.encode(func() int {
+				pb = b.prepareBatch(events)
+				if pb == nil {
+					return 0
+				}
+				return len(pb.body.buf)
+			})
+			defer freeBytes()
+			if pb == nil {
+				return
+			}
+			freeSlot := b.pipeline.acquireSend(pb.destination())
+			defer freeSlot()
+			b.sendBatch(pb)
+		}(events)
+	}
+	wg.Wait()
+}
+
 type httpError interface {
 	Timeout() bool
 }
 
+// preparedBatch is a batch that has been encoded and compressed, ready to be
+// sent. Only the request body is kept: the uncompressed encoding is dropped
+// once it is compressed, so a batch waiting to be sent holds no more than the
+// body the in-flight bytes cap counts.
+type preparedBatch struct {
+	events     []*Event
+	numEncoded int
+	encodedLen int
+	start      time.Time
+	encoder    BatchEncoder
+	body       *requestBody
+
+	// attributes common to the entire batch
+	apiHost, writeKey, dataset string
+}
+
+// destination identifies where the batch is going, for per-destination
+// limits. It matches the key batchAgg.Add sorts events by.
+func (pb *preparedBatch) destination() string {
+	return fmt.Sprintf("%s_%s_%s", pb.apiHost, pb.writeKey, pb.dataset)
+}
+
 func (b *batchAgg) fireBatch(events []*Event) {
+	if pb := b.prepareBatch(events); pb != nil {
+		b.sendBatch(pb)
+	}
+}
+
+// prepareBatch encodes and compresses events. It returns nil if there is
+// nothing to send because none of them could be encoded.
+func (b *batchAgg) prepareBatch(events []*Event) *preparedBatch {
 	start := time.Now().UTC()
 	if b.testNower != nil {
 		start = b.testNower.Now()
 	}
 	if len(events) == 0 {
 		// we managed to create a batch key with no events. odd. move on.
-		return
+		return nil
 	}
 
-	var numEncoded int
-	var encEvs []byte
-	var contentType string
-	if b.enableMsgpackEncoding {
-		contentType = "application/msgpack"
-		encEvs, numEncoded = b.encodeBatchMsgp(events)
-	} else {
-		contentType = "application/json"
-		encEvs, numEncoded = b.encodeBatchJSON(events)
-	}
+	pb := &preparedBatch{events: events, start: start, encoder: b.encoder()}
+	encoded, numEncoded := b.encodeBatch(pb.encoder, events)
+	pb.encodedLen, pb.numEncoded = len(encoded), numEncoded
 	// if we failed to encode any events skip this batch
-	if numEncoded == 0 {
-		return
+	if pb.numEncoded == 0 {
+		return nil
 	}
 
 	// get some attributes common to this entire batch up front off the first
 	// valid event (some may be nil)
-	var apiHost, writeKey, dataset string
 	for _, ev := range events {
 		if ev != nil {
-			apiHost = ev.APIHost
-			writeKey = ev.APIKey
-			dataset = ev.Dataset
+			pb.apiHost = ev.APIHost
+			pb.writeKey = ev.APIKey
+			pb.dataset = ev.Dataset
 			break
 		}
 	}
 
-	url, err := url.Parse(apiHost)
+	pb.body = b.buildBody(encoded)
+	return pb
+}
+
+// sendBatch POSTs a prepared batch and sends a response for each of its
+// events.
+func (b *batchAgg) sendBatch(pb *preparedBatch) {
+	defer pb.body.release()
+	events, numEncoded, start := pb.events, pb.numEncoded, pb.start
+
+	url, err := url.Parse(pb.apiHost)
 	if err != nil {
 		end := time.Now().UTC()
 		if b.testNower != nil {
@@ -304,13 +528,13 @@ func (b *batchAgg) fireBatch(events []*Event) {
 		}
 		dur := end.Sub(start)
 		b.metrics.Increment("send_errors")
+		b.counters.add(counterSendErrors, 1)
 		for _, ev := range events {
 			// Pass the parsing error down responses channel for each event that
 			// didn't already error during encoding
 			if ev != nil {
-				b.enqueueResponse(Response{
+				b.respond(ev, Response{
 					Duration: dur / time.Duration(numEncoded),
-					Metadata: ev.Metadata,
 					Err:      err,
 				})
 			}
@@ -319,7 +543,7 @@ func (b *batchAgg) fireBatch(events []*Event) {
 	}
 
 	// build the HTTP request
-	url.Path = path.Join(url.Path, "/1/batch", dataset)
+	url.Path = path.Join(url.Path, "/1/batch", pb.dataset)
 
 	// sigh. dislike
 	userAgent := fmt.Sprintf("libhoney-go/%s", Version)
@@ -329,21 +553,36 @@ func (b *batchAgg) fireBatch(events []*Event) {
 
 	// One retry allowed for connection timeouts.
 	var resp *http.Response
+	bodyLen := len(pb.body.buf)
+	reqStart := time.Now()
 	for try := 0; try < 2; try++ {
 		if try > 0 {
 			b.metrics.Increment("send_retries")
+			b.counters.add(counterRetries, 1)
 		}
 
 		var req *http.Request
-		reqBody, zipped := buildReqReader(encEvs, !b.disableCompression)
-		req, err = http.NewRequest("POST", url.String(), reqBody)
-		req.Header.Set("Content-Type", contentType)
-		if zipped {
-			req.Header.Set("Content-Encoding", "zstd")
+		req, err = http.NewRequest("POST", url.String(), pb.body.reader())
+		req.ContentLength = int64(bodyLen)
+		req.Header.Set("Content-Type", pb.encoder.ContentType())
+		if pb.body.encoding != "" {
+			req.Header.Set("Content-Encoding", pb.body.encoding)
 		}
 
 		req.Header.Set("User-Agent", userAgent)
-		req.Header.Add("X-Honeycomb-Team", writeKey)
+		req.Header.Add("X-Honeycomb-Team", pb.writeKey)
+		// let the modifier read the body (to sign it, say) without consuming
+		// the request's copy
+		req.GetBody = func() (io.ReadCloser, error) {
+			return pb.body.reader(), nil
+		}
+		if b.modifyRequest != nil {
+			req = withBatchInfo(req, BatchInfo{Dataset: pb.dataset, Events: numEncoded})
+			if err = b.modifyRequest(req); err != nil {
+				req.Body.Close()
+				break
+			}
+		}
 		// send off batch!
 		resp, err = b.httpClient.Do(req)
 
@@ -352,15 +591,27 @@ func (b *batchAgg) fireBatch(events []*Event) {
 		}
 		break
 	}
+	reqDur := time.Since(reqStart)
 	end := time.Now().UTC()
 	if b.testNower != nil {
 		end = b.testNower.Now()
 	}
 	dur := end.Sub(start)
 
+	// record the batch's shape and request time whether or not the POST
+	// succeeded; slow or failing requests are the ones worth seeing
+	if dm, ok := b.metrics.(DistributionMetrics); ok {
+		dm.Timing("batch_request_duration_ms", float64(reqDur)/float64(time.Millisecond))
+		dm.Histogram("batch_events", numEncoded)
+		dm.Histogram("batch_bytes_encoded", pb.encodedLen)
+		dm.Histogram("batch_bytes_compressed", bodyLen)
+		dm.Histogram("batch_compression_ratio", float64(pb.encodedLen)/float64(bodyLen))
+	}
+
 	// if the entire HTTP POST failed, send a failed response for every event
 	if err != nil {
 		b.metrics.Increment("send_errors")
+		b.counters.add(counterSendErrors, 1)
 		// Pass the top-level send error down responses channel for each event
 		// that didn't already error during encoding
 		b.enqueueErrResponses(err, events, dur/time.Duration(numEncoded))
@@ -371,10 +622,14 @@ func (b *batchAgg) fireBatch(events []*Event) {
 	// ok, the POST succeeded, let's process each individual response
 	b.metrics.Increment("batches_sent")
 	b.metrics.Count("messages_sent", numEncoded)
+	b.counters.add(counterBatchesSent, 1)
+	b.counters.add(counterSent, int64(numEncoded))
+	b.counters.add(counterBytesSent, int64(bodyLen))
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		b.metrics.Increment("send_errors")
+		b.counters.add(counterSendErrors, 1)
 
 		var err error
 		var body []byte
@@ -403,11 +658,10 @@ func (b *batchAgg) fireBatch(events []*Event) {
 				http.StatusText(resp.StatusCode),
 			)
 			if ev != nil {
-				b.enqueueResponse(Response{
+				b.respond(ev, Response{
 					StatusCode: resp.StatusCode,
 					Body:       body,
 					Duration:   dur / time.Duration(numEncoded),
-					Metadata:   ev.Metadata,
 					Err:        err,
 				})
 			}
@@ -416,15 +670,11 @@ func (b *batchAgg) fireBatch(events []*Event) {
 	}
 
 	// decode the responses
-	var batchResponses []Response
-	if resp.Header.Get("Content-Type") == "application/msgpack" {
-		err = msgpack.NewDecoder(resp.Body).Decode(&batchResponses)
-	} else {
-		err = json.NewDecoder(resp.Body).Decode(&batchResponses)
-	}
+	batchResponses, err := pb.encoder.DecodeResponses(resp.Header.Get("Content-Type"), resp.Body)
 	if err != nil {
 		// if we can't decode the responses, just error out all of them
 		b.metrics.Increment("response_decode_errors")
+		b.counters.add(counterResponseDecodeErrors, 1)
 		b.enqueueErrResponses(err, events, dur/time.Duration(numEncoded))
 		return
 	}
@@ -443,180 +693,90 @@ func (b *batchAgg) fireBatch(events []*Event) {
 		if eIdx == len(events) { // just in case
 			break
 		}
-		resp.Metadata = events[eIdx].Metadata
-		b.enqueueResponse(resp)
+		b.respond(events[eIdx], resp)
 		eIdx++
 	}
 }
 
-// create the JSON for this event list manually so that we can send
-// responses down the response queue for any that fail to marshal
-func (b *batchAgg) encodeBatchJSON(events []*Event) ([]byte, int) {
-	// track first vs. rest events for commas
-	first := true
-	// track how many we successfully encode for later bookkeeping
-	var numEncoded int
-	buf := bytes.Buffer{}
-	buf.WriteByte('[')
-	bytesTotal := 1
-	// ok, we've got our array, let's populate it with JSON events
-	for i, ev := range events {
-		if !first {
-			buf.WriteByte(',')
-			bytesTotal++
-		}
-		first = false
-		evByt, err := json.Marshal(ev)
-		if err != nil {
-			b.enqueueResponse(Response{
-				Err:      err,
-				Metadata: ev.Metadata,
-			})
-			// nil out the invalid Event so we can line up sent Events with server
-			// responses if needed. don't delete to preserve slice length.
-			events[i] = nil
-			continue
-		}
-		// if the event is too large to ever send, add an error to the queue
-		if len(evByt) > apiEventSizeMax {
-			b.enqueueResponse(Response{
-				Err:      fmt.Errorf("event exceeds max event size of %d bytes, API will not accept this event", apiEventSizeMax),
-				Metadata: ev.Metadata,
-			})
-			events[i] = nil
-			continue
-		}
-		bytesTotal += len(evByt)
-
-		// count for the trailing ]
-		if bytesTotal+1 > apiMaxBatchSize {
-			b.reenqueueEvents(events[i:])
-			break
-		}
-		buf.Write(evByt)
-		numEncoded++
+// encoder returns the BatchEncoder for this batch's events.
+func (b *batchAgg) encoder() BatchEncoder {
+	if b.batchEncoder != nil {
+		return b.batchEncoder
+	}
+	if b.enableMsgpackEncoding {
+		return NewMsgpackBatchEncoder(b.keyPolicy)
 	}
-	buf.WriteByte(']')
-	return buf.Bytes(), numEncoded
+	return NewJSONBatchEncoder(b.keyPolicy)
 }
 
-func (b *batchAgg) encodeBatchMsgp(events []*Event) ([]byte, int) {
-	// Msgpack arrays need to be prefixed with the number of elements, but we
-	// don't know in advance how many we'll encode, because the msgpack lib
-	// doesn't do size estimation. Also, the array header is of variable size
-	// based on array length, so we'll need to do some []byte shenanigans at
-	// at the end of this to properly prepend the header.
-
-	var arrayHeader [5]byte
+// encodeBatch encodes events with enc, enforcing the API's size limits so that
+// every BatchEncoder gets the same handling. Events that fail to encode or are
+// too large to ever send get error responses, and events that don't fit in
+// this batch are reenqueued for the next one. It returns the encoded batch and
+// the number of events in it.
+func (b *batchAgg) encodeBatch(enc BatchEncoder, events []*Event) ([]byte, int) {
+	// track how many we successfully encode for later bookkeeping
 	var numEncoded int
-	var buf bytes.Buffer
-
-	// Prepend space for largest possible msgpack array header.
-	buf.Write(arrayHeader[:])
+	w := enc.NewBatchWriter()
 	for i, ev := range events {
-		evByt, err := msgpack.Marshal(ev)
+		size, err := w.AppendEvent(ev)
 		if err != nil {
-			b.enqueueResponse(Response{
-				Err:      err,
-				Metadata: ev.Metadata,
-			})
-			// nil out the invalid Event so we can line up sent Events with server
-			// responses if needed. don't delete to preserve slice length.
-			events[i] = nil
+			b.dropUnencodable(events, i, err)
 			continue
 		}
 		// if the event is too large to ever send, add an error to the queue
-		if len(evByt) > apiEventSizeMax {
-			b.enqueueResponse(Response{
-				Err:      fmt.Errorf("event exceeds max event size of %d bytes, API will not accept this event", apiEventSizeMax),
-				Metadata: ev.Metadata,
-			})
-			events[i] = nil
+		if size > apiEventSizeMax {
+			w.Rewind()
+			b.dropTooLarge(events, i)
 			continue
 		}
-
-		if buf.Len()+len(evByt) > apiMaxBatchSize {
+		if w.Len() > apiMaxBatchSize {
+			w.Rewind()
 			b.reenqueueEvents(events[i:])
+			clearEvents(events[i:])
 			break
 		}
-
-		buf.Write(evByt)
 		numEncoded++
 	}
+	return w.Finish(), numEncoded
+}
 
-	headerBuf := bytes.NewBuffer(arrayHeader[:0])
-	msgpack.NewEncoder(headerBuf).EncodeArrayLen(numEncoded)
-
-	// Shenanigans. Chop off leading bytes we don't need, then copy in header.
-	byts := buf.Bytes()[len(arrayHeader)-headerBuf.Len():]
-	copy(byts, headerBuf.Bytes())
+// dropUnencodable responds with err for events[i], which couldn't be encoded.
+func (b *batchAgg) dropUnencodable(events []*Event, i int, err error) {
+	ev := events[i]
+	if _, ok := err.(*InvalidKeyError); ok {
+		b.counters.dropped(DropReasonInvalidKey)
+	} else {
+		b.counters.dropped(DropReasonEncodeError)
+	}
+	// nil out the invalid Event so we can line up sent Events with server
+	// responses if needed. don't delete to preserve slice length.
+	events[i] = nil
+	b.respond(ev, Response{Err: err})
+}
 
-	return byts, numEncoded
+// dropTooLarge responds with an error for events[i], which is too large to
+// ever send.
+func (b *batchAgg) dropTooLarge(events []*Event, i int) {
+	ev := events[i]
+	b.counters.dropped(DropReasonTooLarge)
+	events[i] = nil
+	b.respond(ev, Response{
+		Err: fmt.Errorf("event exceeds max event size of %d bytes, API will not accept this event", apiEventSizeMax),
+	})
 }
 
 func (b *batchAgg) enqueueErrResponses(err error, events []*Event, duration time.Duration) {
 	for _, ev := range events {
 		if ev != nil {
-			b.enqueueResponse(Response{
+			b.respond(ev, Response{
 				Err:      err,
 				Duration: duration,
-				Metadata: ev.Metadata,
 			})
 		}
 	}
 }
 
-var zstdBufferPool sync.Pool
-
-type pooledReader struct {
-	*bytes.Reader
-	buf []byte
-}
-
-func (r *pooledReader) Close() error {
-	zstdBufferPool.Put(r.buf[:0])
-	r.Reader = nil
-	r.buf = nil
-	return nil
-}
-
-// Instantiating a new encoder is expensive, so use a global one.
-// EncodeAll() is concurrency-safe.
-var zstdEncoder *zstd.Encoder
-
-func init() {
-	var err error
-	zstdEncoder, err = zstd.NewWriter(
-		nil,
-		// Compression level 2 gives a good balance of speed and compression.
-		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(2)),
-		// zstd allocates 2 * GOMAXPROCS * window size, so use a small window.
-		// Most honeycomb messages are smaller than this.
-		zstd.WithWindowSize(1<<16),
-	)
-	if err != nil {
-		panic(err)
-	}
-}
-
-// buildReqReader returns an io.Reader and a boolean, indicating whether or not
-// the io.Reader is compressed.
-func buildReqReader(jsonEncoded []byte, compress bool) (io.ReadCloser, bool) {
-	if compress {
-		var buf []byte
-		if found, ok := zstdBufferPool.Get().([]byte); ok {
-			buf = found[:0]
-		}
-
-		buf = zstdEncoder.EncodeAll(jsonEncoded, buf)
-		return &pooledReader{
-			Reader: bytes.NewReader(buf),
-			buf:    buf,
-		}, true
-	}
-	return ioutil.NopCloser(bytes.NewReader(jsonEncoded)), false
-}
-
 // nower to make testing easier
 type nower interface {
 	Now() time.Time
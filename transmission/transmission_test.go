@@ -112,6 +112,26 @@ func TestHnyTxAdd(t *testing.T) {
 		"overflow error should have been pushed into channel")
 }
 
+func TestHnyTxStats(t *testing.T) {
+	hnyTx := &Honeycomb{
+		Logger:   &nullLogger{},
+		Metrics:  &nullMetrics{},
+		counters: &counters{},
+	}
+	hnyTx.muster.Work = make(chan interface{}, 2)
+	hnyTx.responses = make(chan Response, 2)
+
+	e := &Event{Metadata: "mmeetta"}
+	hnyTx.Add(e)
+	hnyTx.Add(e)
+	hnyTx.Add(e)
+
+	stats := hnyTx.Stats()
+	testEquals(t, stats.QueueLength, 2)
+	testEquals(t, stats.Dropped[DropReasonQueueOverflow], int64(1))
+	testEquals(t, stats.Dropped[DropReasonTooLarge], int64(0))
+}
+
 type FakeRoundTripper struct {
 	req     *http.Request
 	reqBody string
@@ -380,6 +400,107 @@ func TestTxSendSingle(t *testing.T) {
 	})
 }
 
+func TestTxSendStats(t *testing.T) {
+	frt := &FakeRoundTripper{}
+	b := &batchAgg{
+		httpClient: &http.Client{Transport: &OneTimeoutRountTripper{
+			FakeRoundTripper: frt,
+			doTimeout:        true,
+		}},
+		testNower: &fakeNower{},
+		responses: make(chan Response, 2),
+		metrics:   &nullMetrics{},
+		counters:  &counters{},
+	}
+	frt.resp = &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202},{"status":202}]`)),
+	}
+	for i := 0; i < 2; i++ {
+		b.Add(&Event{
+			Data:    map[string]interface{}{"foo": "bar"},
+			APIHost: "http://fakeHost:8080",
+			APIKey:  "written",
+			Dataset: "ds1",
+		})
+	}
+	b.Fire(&testNotifier{})
+
+	stats := b.counters.snapshot()
+	testEquals(t, stats.BatchesSent, int64(1))
+	testEquals(t, stats.Sent, int64(2))
+	testEquals(t, stats.Retries, int64(1))
+	testEquals(t, stats.SendErrors, int64(0))
+	testEquals(t, stats.BytesSent > 0, true)
+}
+
+type recordingMetrics struct {
+	nullMetrics
+	timings    map[string]interface{}
+	histograms map[string]interface{}
+}
+
+func (m *recordingMetrics) Timing(name string, val interface{}) {
+	m.timings[name] = val
+}
+
+func (m *recordingMetrics) Histogram(name string, val interface{}) {
+	m.histograms[name] = val
+}
+
+func TestFireBatchDistributionMetrics(t *testing.T) {
+	metrics := &recordingMetrics{
+		timings:    map[string]interface{}{},
+		histograms: map[string]interface{}{},
+	}
+	frt := &FakeRoundTripper{
+		resp: &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"status":202},{"status":202}]`)),
+		},
+	}
+	b := &batchAgg{
+		httpClient: &http.Client{Transport: frt},
+		testNower:  &fakeNower{},
+		responses:  make(chan Response, 2),
+		metrics:    metrics,
+	}
+	events := []*Event{
+		{Data: map[string]interface{}{"a": 1}, APIHost: "http://fakeHost:8080", APIKey: "written", Dataset: "ds1"},
+		{Data: map[string]interface{}{"b": 2}, APIHost: "http://fakeHost:8080", APIKey: "written", Dataset: "ds1"},
+	}
+	b.fireBatch(events)
+	for range events {
+		testEquals(t, (<-b.responses).Err, nil)
+	}
+
+	if _, ok := metrics.timings["batch_request_duration_ms"]; !ok {
+		t.Error("expected batch_request_duration_ms timing")
+	}
+	testEquals(t, metrics.histograms["batch_events"], 2)
+	testEquals(t, metrics.histograms["batch_bytes_encoded"], len(`[{"data":{"a":1}},{"data":{"b":2}}]`))
+	if _, ok := metrics.histograms["batch_bytes_compressed"].(int); !ok {
+		t.Error("expected batch_bytes_compressed histogram")
+	}
+	if _, ok := metrics.histograms["batch_compression_ratio"].(float64); !ok {
+		t.Error("expected batch_compression_ratio histogram")
+	}
+
+	// a failed POST still records how long it took
+	metrics.timings = map[string]interface{}{}
+	metrics.histograms = map[string]interface{}{}
+	frt.resp = nil
+	frt.respErr = errors.New("connection refused")
+	b.fireBatch(events)
+	for range events {
+		testNotEquals(t, (<-b.responses).Err, nil)
+	}
+	if _, ok := metrics.timings["batch_request_duration_ms"]; !ok {
+		t.Error("expected batch_request_duration_ms timing for a failed POST")
+	}
+	testEquals(t, metrics.histograms["batch_events"], 2)
+}
+
 // test the details of handling batch behavior on a batch with a single dataset
 func TestTxSendBatchSingleDataset(t *testing.T) {
 	tsts := []struct {
@@ -682,6 +803,85 @@ func TestRenqueueEventsAfterOverflow(t *testing.T) {
 	})
 }
 
+// allResponsesRoundTripper accepts every event in an uncompressed JSON batch.
+type allResponsesRoundTripper struct {
+	err error
+}
+
+func (a *allResponsesRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	var evs []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&evs); err != nil {
+		return nil, err
+	}
+	body := "[" + strings.TrimSuffix(strings.Repeat(`{"status":202},`, len(evs)), ",") + "]"
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+// Every event handed to the batch gets exactly one response and is released
+// exactly once afterwards, including events that overflow into later batches.
+func TestFireBatchReleasesEvents(t *testing.T) {
+	for _, tc := range []struct {
+		sendErr  error
+		pipeline *pipeline
+	}{
+		{nil, nil},
+		{errors.New("connection refused"), nil},
+		{nil, newPipeline(2, 1, 10*1000*1000)},
+	} {
+		b := &batchAgg{
+			httpClient:         &http.Client{Transport: &allResponsesRoundTripper{err: tc.sendErr}},
+			responses:          make(chan Response, 200),
+			metrics:            &nullMetrics{},
+			disableCompression: true,
+			pipeline:           tc.pipeline,
+		}
+		if tc.pipeline == nil {
+			// fakeNower isn't safe for the pipeline's concurrent batches
+			b.testNower = &fakeNower{}
+		}
+
+		var lock sync.Mutex
+		released := map[*Event]int{}
+		releaseFn := func(ev *Event) {
+			lock.Lock()
+			released[ev]++
+			lock.Unlock()
+		}
+
+		bigData := map[string]interface{}{"reallyBigColumn": randomString(99 * 1000)}
+		datas := []map[string]interface{}{
+			{"unencodable": make(chan int)},
+			{"tooLarge": randomString(1024 * 1024)},
+		}
+		for i := 0; i < 150; i++ {
+			datas = append(datas, bigData)
+		}
+		for i, data := range datas {
+			b.Add(&Event{
+				Data:     data,
+				APIHost:  "http://fakeHost:8080",
+				APIKey:   "written",
+				Dataset:  fmt.Sprintf("ds%d", i%2),
+				Metadata: i,
+				Release:  releaseFn,
+			})
+		}
+		b.Fire(&testNotifier{})
+
+		testEquals(t, len(b.responses), len(datas))
+		testEquals(t, len(released), len(datas))
+		for _, n := range released {
+			testEquals(t, n, 1)
+		}
+	}
+}
+
 type testRoundTripper struct {
 	callCount int
 }
@@ -0,0 +1,126 @@
+package transmission
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Defaults for the HTTP options on Honeycomb.
+const (
+	DefaultRequestTimeout      = 60 * time.Second
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+)
+
+// RoundTripper returns the RoundTripper the sender makes requests with:
+// Transport if it is set, otherwise a transport of the sender's own built from
+// its connection options. The same one is returned on every call, so other
+// requests to Honeycomb (such as verifying the API key) can share the sender's
+// connections and settings.
+func (h *Honeycomb) RoundTripper() http.RoundTripper {
+	if h.Transport != nil {
+		return h.Transport
+	}
+	h.transportOnce.Do(func() {
+		h.transport = h.newTransport()
+	})
+	return h.transport
+}
+
+// newTransport builds a dedicated transport, so that the sender's connection
+// pool isn't shared with the rest of the process through
+// http.DefaultTransport.
+func (h *Honeycomb) newTransport() *http.Transport {
+	keepAlive := h.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	idleConnsPerHost := h.MaxIdleConnsPerHost
+	if idleConnsPerHost == 0 {
+		idleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	idleConnTimeout := h.IdleConnTimeout
+	if idleConnTimeout == 0 {
+		idleConnTimeout = DefaultIdleConnTimeout
+	}
+	proxy := h.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: keepAlive,
+	}
+	t := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   idleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	attemptHTTP2(t, !h.DisableHTTP2)
+	if h.TLSConfig != nil {
+		t.TLSClientConfig = h.TLSConfig.Clone()
+	}
+	if h.DisableHTTP2 {
+		// a non-nil, empty TLSNextProto turns off HTTP/2
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
+
+// newHTTPClient returns the client shared by all of the sender's batches.
+func (h *Honeycomb) newHTTPClient() *http.Client {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = DefaultRequestTimeout
+	} else if timeout < 0 {
+		timeout = 0
+	}
+	return &http.Client{
+		Transport: h.RoundTripper(),
+		Timeout:   timeout,
+	}
+}
+
+// NewTLSConfig returns a TLS config for Honeycomb.TLSConfig. If caFile is set,
+// the PEM-encoded certificates in it are trusted alongside the system's. If
+// certFile and keyFile are set, the PEM-encoded certificate and key in them
+// are presented as a client certificate.
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
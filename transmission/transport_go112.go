@@ -0,0 +1,10 @@
+//go:build !go1.13
+// +build !go1.13
+
+package transmission
+
+import "net/http"
+
+// attemptHTTP2 does nothing before Go 1.13, which has no ForceAttemptHTTP2;
+// there a Transport tries HTTP/2 only when TLSConfig isn't set.
+func attemptHTTP2(t *http.Transport, attempt bool) {}
@@ -0,0 +1,11 @@
+//go:build go1.13
+// +build go1.13
+
+package transmission
+
+import "net/http"
+
+// attemptHTTP2 sets whether t tries HTTP/2 despite its custom dialer.
+func attemptHTTP2(t *http.Transport, attempt bool) {
+	t.ForceAttemptHTTP2 = attempt
+}
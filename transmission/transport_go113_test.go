@@ -0,0 +1,20 @@
+//go:build go1.13
+// +build go1.13
+
+package transmission
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+)
+
+func TestTransportForceAttemptHTTP2(t *testing.T) {
+	h := &Honeycomb{}
+	testEquals(t, h.newTransport().ForceAttemptHTTP2, true)
+
+	h = &Honeycomb{DisableHTTP2: true}
+	tr := h.newTransport()
+	testEquals(t, tr.ForceAttemptHTTP2, false)
+	testEquals(t, tr.TLSNextProto, map[string]func(string, *tls.Conn) http.RoundTripper{})
+}
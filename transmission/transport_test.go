@@ -0,0 +1,223 @@
+package transmission
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultTransport(t *testing.T) {
+	h := &Honeycomb{MaxBatchSize: 1, PendingWorkCapacity: 1}
+	testOK(t, h.Start())
+	tr, ok := h.RoundTripper().(*http.Transport)
+	testEquals(t, ok, true)
+	testNotEquals(t, tr, http.DefaultTransport)
+	testEquals(t, tr.MaxIdleConnsPerHost, DefaultMaxIdleConnsPerHost)
+	testEquals(t, tr.IdleConnTimeout, DefaultIdleConnTimeout)
+	testEquals(t, tr.TLSNextProto == nil, true)
+	testEquals(t, h.httpClient.Timeout, DefaultRequestTimeout)
+	testEquals(t, h.httpClient.Transport, http.RoundTripper(tr))
+
+	// every batch shares one client, which survives restarts from Flush
+	client := h.httpClient
+	testEquals(t, h.muster.BatchMaker().(*batchAgg).httpClient, client)
+	testEquals(t, h.muster.BatchMaker().(*batchAgg).httpClient, client)
+	testOK(t, h.Stop())
+	testOK(t, h.Start())
+	testEquals(t, h.httpClient, client)
+	testEquals(t, h.RoundTripper(), http.RoundTripper(tr))
+	testOK(t, h.Stop())
+}
+
+func TestTransportOptions(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.example:3128")
+	h := &Honeycomb{
+		MaxBatchSize:        1,
+		PendingWorkCapacity: 1,
+		Timeout:             -1,
+		MaxIdleConnsPerHost: 3,
+		IdleConnTimeout:     time.Minute,
+		TLSConfig:           &tls.Config{ServerName: "example"},
+		Proxy:               http.ProxyURL(proxyURL),
+		DisableHTTP2:        true,
+	}
+	testOK(t, h.Start())
+	defer h.Stop()
+	tr := h.RoundTripper().(*http.Transport)
+	testEquals(t, tr.MaxIdleConnsPerHost, 3)
+	testEquals(t, tr.IdleConnTimeout, time.Minute)
+	testEquals(t, tr.TLSClientConfig.ServerName, "example")
+	testEquals(t, len(tr.TLSNextProto), 0)
+	testEquals(t, tr.TLSNextProto == nil, false)
+	proxy, err := tr.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "api.honeycomb.io"}})
+	testOK(t, err)
+	testEquals(t, proxy, proxyURL)
+	testEquals(t, h.httpClient.Timeout, time.Duration(0))
+
+	// an explicit Transport is used as is
+	frt := &FakeRoundTripper{}
+	h = &Honeycomb{Transport: frt, Timeout: time.Second}
+	testEquals(t, h.RoundTripper(), http.RoundTripper(frt))
+	testEquals(t, h.newHTTPClient().Timeout, time.Second)
+}
+
+func TestStopClosesIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			select {
+			case closed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	h := &Honeycomb{MaxBatchSize: 1, PendingWorkCapacity: 1}
+	testOK(t, h.Start())
+	resp, err := h.httpClient.Get(server.URL)
+	testOK(t, err)
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	testOK(t, h.Stop())
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection wasn't closed")
+	}
+}
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1, usable by
+// both servers and clients, and its key to dir as PEM files.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testOK(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "libhoney test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	testOK(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	testOK(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	testOK(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	testOK(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libhoney-tls")
+	testOK(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+
+	serverConfig, err := NewTLSConfig(certFile, certFile, keyFile)
+	testOK(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testEquals(t, len(r.TLS.PeerCertificates), 1)
+		w.Write([]byte(`[{"status":202}]`))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: serverConfig.Certificates,
+		ClientCAs:    serverConfig.RootCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	// the refused handshake below is expected
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	clientConfig, err := NewTLSConfig(certFile, certFile, keyFile)
+	testOK(t, err)
+	h := &Honeycomb{
+		MaxBatchSize:        1,
+		BatchTimeout:        time.Millisecond,
+		PendingWorkCapacity: 1,
+		TLSConfig:           clientConfig,
+	}
+	testOK(t, h.Start())
+	defer h.Stop()
+	h.Add(&Event{
+		APIHost: server.URL,
+		APIKey:  "written",
+		Dataset: "ds",
+		Data:    map[string]interface{}{"a": 1},
+	})
+	select {
+	case resp := <-h.TxResponses():
+		testOK(t, resp.Err)
+		testEquals(t, resp.StatusCode, 202)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a response")
+	}
+
+	// without a client certificate the server refuses the connection
+	noClientCert, err := NewTLSConfig(certFile, "", "")
+	testOK(t, err)
+	h2 := &Honeycomb{
+		MaxBatchSize:        1,
+		BatchTimeout:        time.Millisecond,
+		PendingWorkCapacity: 1,
+		TLSConfig:           noClientCert,
+	}
+	testOK(t, h2.Start())
+	defer h2.Stop()
+	h2.Add(&Event{APIHost: server.URL, Data: map[string]interface{}{"a": 1}})
+	select {
+	case resp := <-h2.TxResponses():
+		testErr(t, resp.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a response")
+	}
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libhoney-tls")
+	testOK(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+	notPEM := filepath.Join(dir, "empty.pem")
+	testOK(t, ioutil.WriteFile(notPEM, []byte("nothing here"), 0600))
+
+	_, err = NewTLSConfig(filepath.Join(dir, "missing.pem"), "", "")
+	testErr(t, err)
+	_, err = NewTLSConfig(notPEM, "", "")
+	testErr(t, err)
+	_, err = NewTLSConfig("", certFile, "")
+	testErr(t, err)
+	_, err = NewTLSConfig("", keyFile, keyFile)
+	testErr(t, err)
+
+	config, err := NewTLSConfig("", "", "")
+	testOK(t, err)
+	testEquals(t, config.RootCAs == nil, true)
+	testEquals(t, len(config.Certificates), 0)
+}
@@ -62,6 +62,7 @@ func (w *WriterSender) Add(ev *Event) {
 		Metadata: ev.Metadata,
 	}
 	w.SendResponse(resp)
+	release(ev)
 }
 
 func (w *WriterSender) TxResponses() chan Response {
@@ -0,0 +1,132 @@
+package libhoney
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Upper bounds on the batching settings beyond which a value is almost
+// certainly a mistake (such as a byte count where an event count was meant).
+const (
+	maxValidBatchSize           = 100000
+	maxValidConcurrentBatches   = 10000
+	maxValidPendingWorkCapacity = 10000000
+	maxValidSendFrequency       = time.Hour
+	maxValidSampleRate          = math.MaxInt32
+	minValidSendFrequency       = time.Millisecond
+	maxValidAPIKeyLength        = 256
+)
+
+// ConfigErrors is returned by Config.Validate and ClientConfig.Validate. It
+// holds every problem found rather than only the first one.
+type ConfigErrors []error
+
+func (ce ConfigErrors) Error() string {
+	msgs := make([]string, len(ce))
+	for i, err := range ce {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid libhoney config: %s", strings.Join(msgs, "; "))
+}
+
+// errOrNil returns ce as an error, or nil if it is empty, so that callers get
+// a true nil interface when there are no problems.
+func (ce ConfigErrors) errOrNil() error {
+	if len(ce) == 0 {
+		return nil
+	}
+	return ce
+}
+
+// Validate checks the Config for mistakes that would otherwise only surface
+// once events are sent: a malformed APIHost or API key, conflicting fields
+// and out-of-range batching settings. Zero values are valid and mean "use
+// the default". It returns a ConfigErrors listing every problem, or nil. Init
+// calls Validate and refuses to start from an invalid config.
+func (conf Config) Validate() error {
+	var errs ConfigErrors
+	if conf.APIKey != "" && conf.WriteKey != "" {
+		errs = append(errs, errors.New("APIKey and the deprecated WriteKey are both set; set only APIKey"))
+	}
+	errs = append(errs, validateAPIKey("APIKey", conf.APIKey)...)
+	errs = append(errs, validateAPIKey("WriteKey", conf.WriteKey)...)
+	errs = append(errs, validateAPIHost(conf.APIHost)...)
+	errs = append(errs, validateSampleRate(conf.SampleRate)...)
+
+	if conf.Output != nil && conf.Transmission != nil {
+		errs = append(errs, errors.New("Output and Transmission are both set; Output is deprecated, set only Transmission"))
+	}
+	if conf.MaxBatchSize > maxValidBatchSize {
+		errs = append(errs, fmt.Errorf("MaxBatchSize %d is larger than the maximum of %d", conf.MaxBatchSize, maxValidBatchSize))
+	}
+	if conf.SendFrequency < 0 {
+		errs = append(errs, fmt.Errorf("SendFrequency %s is negative", conf.SendFrequency))
+	} else if conf.SendFrequency != 0 && conf.SendFrequency < minValidSendFrequency {
+		errs = append(errs, fmt.Errorf("SendFrequency %s is shorter than the minimum of %s", conf.SendFrequency, minValidSendFrequency))
+	} else if conf.SendFrequency > maxValidSendFrequency {
+		errs = append(errs, fmt.Errorf("SendFrequency %s is longer than the maximum of %s", conf.SendFrequency, maxValidSendFrequency))
+	}
+	if conf.MaxConcurrentBatches > maxValidConcurrentBatches {
+		errs = append(errs, fmt.Errorf("MaxConcurrentBatches %d is larger than the maximum of %d", conf.MaxConcurrentBatches, maxValidConcurrentBatches))
+	}
+	if conf.PendingWorkCapacity > maxValidPendingWorkCapacity {
+		errs = append(errs, fmt.Errorf("PendingWorkCapacity %d is larger than the maximum of %d", conf.PendingWorkCapacity, maxValidPendingWorkCapacity))
+	}
+	return errs.errOrNil()
+}
+
+// Validate checks the ClientConfig for a malformed APIHost or API key and an
+// out-of-range sample rate. Zero values are valid and mean "use the default".
+// It returns a ConfigErrors listing every problem, or nil. NewClient calls
+// Validate and refuses to create a Client from an invalid config.
+func (conf ClientConfig) Validate() error {
+	var errs ConfigErrors
+	errs = append(errs, validateAPIKey("APIKey", conf.APIKey)...)
+	errs = append(errs, validateAPIHost(conf.APIHost)...)
+	errs = append(errs, validateSampleRate(conf.SampleRate)...)
+	return errs.errOrNil()
+}
+
+func validateAPIKey(field, key string) []error {
+	if key == "" {
+		return nil
+	}
+	if len(key) > maxValidAPIKeyLength {
+		return []error{fmt.Errorf("%s is %d characters long; Honeycomb API keys are much shorter", field, len(key))}
+	}
+	for _, r := range key {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return []error{fmt.Errorf("%s contains whitespace or non-printable characters", field)}
+		}
+	}
+	return nil
+}
+
+func validateAPIHost(host string) []error {
+	if host == "" {
+		return nil
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return []error{fmt.Errorf("APIHost %q is not a valid URL: %s", host, err)}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return []error{fmt.Errorf("APIHost %q must be an http or https URL", host)}
+	}
+	if u.Host == "" {
+		return []error{fmt.Errorf("APIHost %q has no host", host)}
+	}
+	return nil
+}
+
+func validateSampleRate(rate uint) []error {
+	if uint64(rate) > maxValidSampleRate {
+		return []error{fmt.Errorf("SampleRate %d is larger than the maximum of %d", rate, maxValidSampleRate)}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package libhoney
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+func TestConfigValidate(t *testing.T) {
+	testOK(t, Config{}.Validate())
+	testOK(t, Config{
+		APIKey:        "abcabc123123defdef456456",
+		APIHost:       "https://api.honeycomb.io/",
+		SampleRate:    10,
+		MaxBatchSize:  100,
+		SendFrequency: time.Second,
+	}.Validate())
+
+	err := Config{
+		APIKey:        "abc",
+		WriteKey:      "def",
+		APIHost:       "api.honeycomb.io",
+		MaxBatchSize:  5000000,
+		SendFrequency: -time.Second,
+		Output:        &MockOutput{},
+		Transmission:  &transmission.MockSender{},
+	}.Validate()
+	testErr(t, err)
+	errs, ok := err.(ConfigErrors)
+	testEquals(t, ok, true)
+	testEquals(t, len(errs), 5, err.Error())
+	for _, want := range []string{"WriteKey", "APIHost", "MaxBatchSize", "SendFrequency", "Output and Transmission"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %q", want, err)
+		}
+	}
+
+	// Init refuses an invalid config and leaves the default client alone
+	before := dc
+	err = Init(Config{APIHost: "://nope", Transmission: &transmission.MockSender{}})
+	_, ok = err.(ConfigErrors)
+	testEquals(t, ok, true)
+	testEquals(t, dc, before)
+}
+
+func TestClientConfigValidate(t *testing.T) {
+	testOK(t, ClientConfig{}.Validate())
+
+	err := ClientConfig{
+		APIKey:  "has a space",
+		APIHost: "http://",
+	}.Validate()
+	testErr(t, err)
+	testEquals(t, len(err.(ConfigErrors)), 2, err.Error())
+
+	c, err := NewClient(ClientConfig{APIHost: "://nope"})
+	testErr(t, err)
+	testEquals(t, c, (*Client)(nil))
+}
@@ -0,0 +1,155 @@
+package libhoney
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// apiKeyCacheTTL is how long a successful key verification is remembered.
+const apiKeyCacheTTL = time.Hour
+
+// DefaultVerifyAPIKeyTimeout is how long NewClient waits for the API key to be
+// verified when ClientConfig.VerifyAPIKey is set.
+const DefaultVerifyAPIKeyTimeout = 10 * time.Second
+
+// APIKeyInfo describes an API key, as returned by VerifyAPIKeyContext.
+type APIKeyInfo struct {
+	// TeamName and TeamSlug identify the team the key belongs to.
+	TeamName string
+	TeamSlug string
+
+	// EnvironmentName and EnvironmentSlug identify the environment the key
+	// belongs to. They are empty for keys from Honeycomb Classic.
+	EnvironmentName string
+	EnvironmentSlug string
+
+	// Permissions lists what the key may be used for, keyed by the API's
+	// permission name (such as "events", "markers" or "createDatasets").
+	Permissions map[string]bool
+}
+
+// CanSendEvents reports whether the key may be used to send events.
+func (i APIKeyInfo) CanSendEvents() bool {
+	return i.Permissions["events"]
+}
+
+// copy returns i with its own Permissions map, so callers can't modify the
+// cached copy.
+func (i APIKeyInfo) copy() APIKeyInfo {
+	perms := make(map[string]bool, len(i.Permissions))
+	for k, v := range i.Permissions {
+		perms[k] = v
+	}
+	i.Permissions = perms
+	return i
+}
+
+type authResponse struct {
+	APIKeyAccess map[string]bool `json:"api_key_access"`
+	Environment  struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	} `json:"environment"`
+	Team struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	} `json:"team"`
+}
+
+type apiKeyCacheEntry struct {
+	info    APIKeyInfo
+	expires time.Time
+}
+
+var (
+	apiKeyCache     = map[string]apiKeyCacheEntry{}
+	apiKeyCacheLock sync.Mutex
+)
+
+// VerifyAPIKeyContext calls out to the Honeycomb API to validate the API key
+// (APIKey, or WriteKey if APIKey is empty) in config, returning details of the
+// team, environment and permissions it belongs to. The request is sent to
+// config.APIHost using config.Transport, or the RoundTripper of
+// config.Transmission if it is a transmission.Honeycomb (whose Headers and
+// RequestModifier are applied to it too), and is bound by ctx.
+//
+// If the key is rejected the error matches ErrInvalidAPIKey under errors.Is;
+// other non-2xx responses are returned as *APIError. Successful results are
+// cached for an hour per API host and key.
+func VerifyAPIKeyContext(ctx context.Context, config Config) (APIKeyInfo, error) {
+	apiKey := config.APIKey
+	if apiKey == "" {
+		apiKey = config.WriteKey
+	}
+	if apiKey == "" {
+		return APIKeyInfo{}, ErrMissingAPIKey
+	}
+	apiHost := config.APIHost
+	if apiHost == "" {
+		apiHost = defaultAPIHost
+	}
+
+	cacheKey := apiHost + "\x00" + apiKey
+	apiKeyCacheLock.Lock()
+	entry, ok := apiKeyCache[cacheKey]
+	apiKeyCacheLock.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.info.copy(), nil
+	}
+
+	client := newAPIClient(apiHost, apiKey, config.Transport, config.Transmission)
+	var resp authResponse
+	if err := client.do(ctx, "GET", nil, &resp, "1", "auth"); err != nil {
+		return APIKeyInfo{}, err
+	}
+	info := APIKeyInfo{
+		TeamName:        resp.Team.Name,
+		TeamSlug:        resp.Team.Slug,
+		EnvironmentName: resp.Environment.Name,
+		EnvironmentSlug: resp.Environment.Slug,
+		Permissions:     resp.APIKeyAccess,
+	}
+	if info.Permissions == nil {
+		info.Permissions = map[string]bool{}
+	}
+
+	apiKeyCacheLock.Lock()
+	apiKeyCache[cacheKey] = apiKeyCacheEntry{
+		info:    info,
+		expires: time.Now().Add(apiKeyCacheTTL),
+	}
+	apiKeyCacheLock.Unlock()
+	return info.copy(), nil
+}
+
+// legacyVerifyError keeps the messages VerifyAPIKey returned before it was
+// built on VerifyAPIKeyContext, while still matching ErrInvalidAPIKey and
+// *APIError under errors.Is and errors.As.
+type legacyVerifyError struct {
+	msg string
+	err error
+}
+
+func (e *legacyVerifyError) Error() string { return e.msg }
+
+func (e *legacyVerifyError) Unwrap() error { return e.err }
+
+// legacyVerifyErr rewrites err, from VerifyAPIKeyContext, as VerifyAPIKey has
+// always reported it.
+func legacyVerifyErr(err error) error {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return err
+	}
+	if apiErr.StatusCode == http.StatusUnauthorized {
+		return &legacyVerifyError{msg: "Write key provided is invalid", err: err}
+	}
+	return &legacyVerifyError{
+		msg: fmt.Sprintf(`Abnormal non-200 response verifying Honeycomb write key: %d
+Response body: %s`, apiErr.StatusCode, apiErr.Body),
+		err: err,
+	}
+}
@@ -0,0 +1,169 @@
+package libhoney
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/honeycombio/libhoney-go/transmission"
+)
+
+func startAuthServer(t *testing.T, hits *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		testEquals(t, r.URL.Path, "/1/auth")
+		testEquals(t, r.Header.Get("User-Agent"), userAgent())
+		if r.Header.Get("X-Honeycomb-Team") != "goodkey" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unknown API key - check your credentials"}`))
+			return
+		}
+		w.Write([]byte(`{
+			"api_key_access": {"events": true, "markers": false},
+			"environment": {"name": "Production", "slug": "production"},
+			"team": {"name": "Honeycomb", "slug": "honeycomb"}
+		}`))
+	}))
+	return server
+}
+
+type countingTransport struct {
+	calls int32
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestVerifyAPIKeyContext(t *testing.T) {
+	var hits int32
+	server := startAuthServer(t, &hits)
+	defer server.Close()
+	transport := &countingTransport{}
+
+	conf := Config{APIKey: "goodkey", APIHost: server.URL, Transport: transport}
+	info, err := VerifyAPIKeyContext(context.Background(), conf)
+	testOK(t, err)
+	testEquals(t, info.TeamSlug, "honeycomb")
+	testEquals(t, info.EnvironmentSlug, "production")
+	testEquals(t, info.CanSendEvents(), true)
+	testEquals(t, info.Permissions["markers"], false)
+	testEquals(t, atomic.LoadInt32(&transport.calls), int32(1))
+
+	// positive results are cached
+	info.Permissions["events"] = false
+	info, err = VerifyAPIKeyContext(context.Background(), conf)
+	testOK(t, err)
+	testEquals(t, info.CanSendEvents(), true)
+	testEquals(t, atomic.LoadInt32(&hits), int32(1))
+
+	team, err := VerifyAPIKey(conf)
+	testOK(t, err)
+	testEquals(t, team, "honeycomb")
+}
+
+func TestVerifyAPIKeyContextInvalid(t *testing.T) {
+	var hits int32
+	server := startAuthServer(t, &hits)
+	defer server.Close()
+
+	conf := Config{APIKey: "badkey", APIHost: server.URL}
+	_, err := VerifyAPIKeyContext(context.Background(), conf)
+	apiErr, ok := err.(*APIError)
+	testEquals(t, ok, true)
+	testEquals(t, apiErr.Is(ErrInvalidAPIKey), true)
+	testEquals(t, apiErr.Message, "unknown API key - check your credentials")
+
+	// failures aren't cached
+	_, err = VerifyAPIKeyContext(context.Background(), conf)
+	testErr(t, err)
+	testEquals(t, atomic.LoadInt32(&hits), int32(2))
+
+	// VerifyAPIKey keeps its original error messages
+	_, err = VerifyAPIKey(conf)
+	testEquals(t, err.Error(), "Write key provided is invalid")
+	legacyErr, ok := err.(*legacyVerifyError)
+	testEquals(t, ok, true)
+	testEquals(t, legacyErr.err.(*APIError).Is(ErrInvalidAPIKey), true)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream down"))
+	}))
+	defer broken.Close()
+	_, err = VerifyAPIKey(Config{APIKey: "goodkey", APIHost: broken.URL})
+	testEquals(t, err.Error(), "Abnormal non-200 response verifying Honeycomb write key: 502\nResponse body: upstream down")
+	legacyErr, ok = err.(*legacyVerifyError)
+	testEquals(t, ok, true)
+	testEquals(t, legacyErr.err.(*APIError).StatusCode, http.StatusBadGateway)
+
+	_, err = VerifyAPIKeyContext(context.Background(), Config{APIHost: server.URL})
+	testEquals(t, err, ErrMissingAPIKey)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = VerifyAPIKeyContext(ctx, Config{APIKey: "uncached", APIHost: server.URL})
+	urlErr, ok := err.(*url.Error)
+	testEquals(t, ok, true)
+	testEquals(t, urlErr.Err, context.Canceled)
+}
+
+func TestVerifyAPIKeyRequestModifier(t *testing.T) {
+	var proxyToken, signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyToken = r.Header.Get("X-Proxy-Token")
+		signature = r.Header.Get("X-Signature")
+		w.Write([]byte(`{"team": {"slug": "honeycomb"}}`))
+	}))
+	defer server.Close()
+
+	sender := &transmission.Honeycomb{
+		Headers: http.Header{"X-Proxy-Token": []string{"secret"}},
+		RequestModifier: func(req *http.Request) error {
+			_, isBatch := transmission.RequestBatchInfo(req)
+			testEquals(t, isBatch, false)
+			req.Header.Set("X-Signature", "signed")
+			return nil
+		},
+	}
+	conf := Config{APIKey: "modifiedkey", APIHost: server.URL, Transmission: sender}
+	_, err := VerifyAPIKeyContext(context.Background(), conf)
+	testOK(t, err)
+	testEquals(t, proxyToken, "secret")
+	testEquals(t, signature, "signed")
+
+	signErr := errors.New("no signing key")
+	sender.RequestModifier = func(*http.Request) error { return signErr }
+	conf.APIKey = "unsignedkey"
+	_, err = VerifyAPIKeyContext(context.Background(), conf)
+	testEquals(t, err, signErr)
+}
+
+func TestNewClientVerifyAPIKey(t *testing.T) {
+	var hits int32
+	server := startAuthServer(t, &hits)
+	defer server.Close()
+
+	_, err := NewClient(ClientConfig{
+		APIKey:       "anotherbadkey",
+		APIHost:      server.URL,
+		Transmission: &transmission.MockSender{},
+		VerifyAPIKey: true,
+	})
+	apiErr, ok := err.(*APIError)
+	testEquals(t, ok, true)
+	testEquals(t, apiErr.Is(ErrInvalidAPIKey), true)
+
+	c, err := NewClient(ClientConfig{
+		APIKey:       "goodkey",
+		APIHost:      server.URL,
+		Transmission: &transmission.MockSender{},
+		VerifyAPIKey: true,
+	})
+	testOK(t, err)
+	c.Close()
+}